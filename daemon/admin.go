@@ -0,0 +1,249 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"strings"
+	"net/http"
+	"io/ioutil"
+	"encoding/json"
+)
+
+import (
+	"github.com/go-pg/pg"
+	log "github.com/Sirupsen/logrus"
+)
+
+/**************************************************************\
+* Worker states
+***************************************************************/
+
+// WorkerState describes what a worker is doing
+type WorkerState struct {
+	Name  string    `json:"name"`           // dispatcher `worker:1`, pool worker `wdj:1`
+	Task  string    `json:"task,omitempty"` // raw task id, empty when idle
+	Since time.Time `json:"since"`          // when current state begins
+}
+
+// StateBoard holds states of all workers
+type StateBoard struct {
+	sync.Mutex
+	states map[string]*WorkerState
+}
+
+// Set will update state of worker, empty task means idle
+func (b *StateBoard) Set(name, task string) {
+	b.Lock()
+	defer b.Unlock()
+	if b.states == nil {
+		b.states = make(map[string]*WorkerState)
+	}
+	b.states[name] = &WorkerState{Name: name, Task: task, Since: time.Now()}
+}
+
+// List returns all worker states sorted by name
+func (b *StateBoard) List() []WorkerState {
+	b.Lock()
+	defer b.Unlock()
+	res := make([]WorkerState, 0, len(b.states))
+	for _, s := range b.states {
+		res = append(res, *s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// States tracks all dispatchers and pool workers
+var States = new(StateBoard)
+
+/**************************************************************\
+* Admin API
+***************************************************************/
+
+func init() {
+	Mux.HandleFunc("/tasks", handleTasks)
+	Mux.HandleFunc("/queue", handleQueue)
+	Mux.HandleFunc("/workers", handleWorkers)
+	Mux.HandleFunc("/fill", handleFill)
+	Mux.HandleFunc("/sources/", handleSources)
+	Mux.HandleFunc("/healthz", handleHealth)
+	Mux.HandleFunc("/readyz", handleReady)
+}
+
+// writeJSON writes v as json response with status code
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("[HTTP] write response failed: %s", err.Error())
+	}
+}
+
+// writeError writes {"error": msg} with status code
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+// Enqueue puts raw task ids into `android_queue`, returns number of inserted rows
+func Enqueue(ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	res, err := Pg.Exec(`INSERT INTO android_queue(id) SELECT unnest(?::TEXT[]) ON CONFLICT DO NOTHING;`, pg.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+// parseTasks accepts a json string or a json array of raw tasks, e.g. `["!com.tencent.mm", "#微信"]`
+// tasks without leading type letter are treated as package name
+func parseTasks(body []byte) (valid []string, invalid []string, err error) {
+	var raw []string
+	if err = json.Unmarshal(body, &raw); err != nil {
+		var single string
+		if err = json.Unmarshal(body, &single); err != nil {
+			return nil, nil, err
+		}
+		raw = []string{single}
+	}
+	for _, id := range raw {
+		if msg := NewMessage(strings.TrimSpace(id)); msg.Valid() {
+			valid = append(valid, msg.String())
+		} else {
+			invalid = append(invalid, id)
+		}
+	}
+	return
+}
+
+// handleTasks: POST /tasks enqueues single or batch tasks
+func handleTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	valid, invalid, err := parseTasks(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "body should be a task string or an array of task strings")
+		return
+	}
+	inserted, err := Enqueue(valid)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Infof("[ADMIN] enqueue %d tasks, %d inserted, %d invalid", len(valid), inserted, len(invalid))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"accepted": len(valid),
+		"inserted": inserted,
+		"invalid":  invalid,
+	})
+}
+
+// handleQueue: GET /queue shows tasks waiting in database and in process
+func handleQueue(w http.ResponseWriter, r *http.Request) {
+	var rows []struct {
+		Type  string
+		Count int64
+	}
+	if _, err := Pg.Query(&rows, `SELECT left(id, 1) AS type, count(*) AS count FROM android_queue GROUP BY 1;`); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	waiting := make(map[string]int64)
+	var depth int64
+	for _, row := range rows {
+		// tasks without leading type letter are packages
+		waiting[NewMessage(row.Type+"_").TypeName()] += row.Count
+		depth += row.Count
+	}
+	queued := make(map[string]int)
+	for _, src := range Sources {
+		queued[src.Name] = src.Queued()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"depth":   depth,                  // tasks in android_queue
+		"waiting": waiting,                // tasks in android_queue by type
+		"claimed": len(Claimed.Pending()), // claimed by this process but not finished
+		"sources": queued,                 // tasks in each source pool queue
+	})
+}
+
+// handleWorkers: GET /workers shows state of every worker and source
+func handleWorkers(w http.ResponseWriter, r *http.Request) {
+	type sourceState struct {
+		Name    string `json:"name"`
+		Paused  bool   `json:"paused"`
+		Workers int    `json:"workers"`
+		Queued  int    `json:"queued"`
+	}
+	sources := make([]sourceState, 0, len(Sources))
+	for _, src := range Sources {
+		sources = append(sources, sourceState{src.Name, src.Paused(), src.Workers, src.Queued()})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sources": sources,
+		"workers": States.List(),
+	})
+}
+
+// handleFill: GET /fill lists fill rate and baseline of every parsed field by source
+func handleFill(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, FillRates.Rates())
+}
+
+// handleSources: POST /sources/{name}/pause and POST /sources/{name}/resume
+func handleSources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/sources/"), "/"), "/")
+	if len(parts) != 2 {
+		writeError(w, http.StatusNotFound, "use /sources/{name}/pause or /sources/{name}/resume")
+		return
+	}
+	var src *Source
+	for _, s := range Sources {
+		if s.Name == parts[0] {
+			src = s
+		}
+	}
+	if src == nil {
+		writeError(w, http.StatusNotFound, "source not enabled: "+parts[0])
+		return
+	}
+	switch parts[1] {
+	case "pause":
+		src.Pause()
+	case "resume":
+		src.Resume()
+	default:
+		writeError(w, http.StatusNotFound, "unknown action: "+parts[1])
+		return
+	}
+	log.Infof("[ADMIN] %s %s", parts[1], src.Name)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": src.Name, "paused": src.Paused()})
+}
+
+// handleHealth: GET /healthz tells daemon is alive
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady: GET /readyz checks postgres connection
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	var n int
+	if _, err := Pg.QueryOne(pg.Scan(&n), `SELECT 1;`); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"encoding/json"
+	"net/http/httptest"
+)
+
+func TestParseTasks(t *testing.T) {
+	valid, invalid, err := parseTasks([]byte(`["!com.tencent.mm", "#微信", "com.autonavi.minimap", "!", ""]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(valid, ","); got != "!com.tencent.mm,#微信,!com.autonavi.minimap" {
+		t.Errorf("valid = %s", got)
+	}
+	if len(invalid) != 2 {
+		t.Errorf("invalid = %v", invalid)
+	}
+
+	if valid, _, err = parseTasks([]byte(`"#王者荣耀"`)); err != nil || len(valid) != 1 {
+		t.Errorf("single task: %v %v", valid, err)
+	}
+	if _, _, err = parseTasks([]byte(`{"id": 1}`)); err == nil {
+		t.Error("object body should be rejected")
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	src := &Source{Name: "wdj", Workers: 1, queue: make(chan *Task, 1), Handle: func(string) error { return nil }}
+	Sources = []*Source{src}
+	defer func() { Sources = nil }()
+
+	for _, c := range []struct {
+		path   string
+		code   int
+		paused bool
+	}{
+		{"/sources/wdj/pause", 200, true},
+		{"/sources/wdj/pause", 200, true},
+		{"/sources/wdj/resume", 200, false},
+		{"/sources/sjqq/pause", 404, false},
+		{"/sources/wdj/stop", 404, false},
+	} {
+		w := httptest.NewRecorder()
+		Mux.ServeHTTP(w, httptest.NewRequest("POST", c.path, nil))
+		if w.Code != c.code {
+			t.Errorf("%s: code = %d, want %d", c.path, w.Code, c.code)
+		}
+		if src.Paused() != c.paused {
+			t.Errorf("%s: paused = %v, want %v", c.path, src.Paused(), c.paused)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	Mux.ServeHTTP(w, httptest.NewRequest("GET", "/sources/wdj/pause", nil))
+	if w.Code != 405 {
+		t.Errorf("GET pause: code = %d, want 405", w.Code)
+	}
+
+	States.Set("wdj:1", "!com.tencent.mm")
+	w = httptest.NewRecorder()
+	Mux.ServeHTTP(w, httptest.NewRequest("GET", "/workers", nil))
+	var res struct {
+		Sources []struct{ Name string }
+		Workers []WorkerState
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Sources) != 1 || len(res.Workers) == 0 || res.Workers[0].Task != "!com.tencent.mm" {
+		t.Errorf("workers = %s", w.Body.String())
+	}
+}
@@ -3,26 +3,34 @@ package main
 import (
 	"os"
 	"fmt"
+	"flag"
+	"sync"
 	"time"
-	"bytes"
+	"context"
 	"strings"
+	"syscall"
+	"os/signal"
 )
 
 import (
 	"github.com/go-pg/pg"
 	"github.com/Vonng/go-android-search/wdj"
 	"github.com/Vonng/go-android-search/sjqq"
+	"github.com/Vonng/go-android-search/search"
 	log "github.com/Sirupsen/logrus"
 )
 
 // ID type indicator
 const (
-	TypePackage  = '!'
-	TypeKeywords = '#'
+	TypePackage   = '!'
+	TypeKeywords  = '#'
+	TypeCategory  = '@' // category listing, ID is `source:category`, e.g. `@wdj:5014_710`
+	TypeChart     = '^' // top chart, ID is `source:chart`, e.g. `^wdj:hot`
+	TypeDeveloper = '&' // developer, ID is vendor name shown in store, e.g. `&腾讯`
 )
 
 // Message hold msg type with one [optional] leading byte and following ID value.
-// If no leading letter of `!@#` is provided, Bundle ID is used as default.
+// If no leading letter of `!#@^&` is provided, Bundle ID is used as default.
 type Message struct {
 	Type byte
 	ID   string
@@ -35,9 +43,10 @@ func NewMessage(msg string) (m Message) {
 	}
 
 	m.Type, m.ID = msg[0], string(msg[1:])
-	if m.Type == TypePackage || m.Type == TypeKeywords {
+	switch m.Type {
+	case TypePackage, TypeKeywords, TypeCategory, TypeChart, TypeDeveloper:
 		return
-	} else {
+	default:
 		m.Type = TypePackage
 		m.ID = msg
 	}
@@ -46,86 +55,224 @@ func NewMessage(msg string) (m Message) {
 
 // Message_Valid tells if this message is valid
 func (m *Message) Valid() bool {
-	return (m.Type == TypePackage || m.Type == TypeKeywords) && len(m.ID) > 0
+	switch m.Type {
+	case TypePackage, TypeKeywords, TypeDeveloper:
+		return len(m.ID) > 0
+	case TypeCategory, TypeChart:
+		source, list := m.List()
+		return isSource(source) && list != ""
+	}
+	return false
+}
+
+// Message_List splits ID of category or chart message into source and list name
+func (m Message) List() (source, list string) {
+	if i := strings.IndexByte(m.ID, ':'); i > 0 {
+		return m.ID[:i], m.ID[i+1:]
+	}
+	return "", m.ID
 }
 
-// Global postgreSQL instance
-var Pg = pg.Connect(&pg.Options{
-	Addr:     ":5432",
-	Database: "meta",
-	User:     "meta",
-	Password: "meta",
-})
+// Message_TypeName returns readable name of message type
+func (m Message) TypeName() string {
+	switch m.Type {
+	case TypePackage:
+		return "package"
+	case TypeKeywords:
+		return "keyword"
+	case TypeCategory:
+		return "category"
+	case TypeChart:
+		return "chart"
+	case TypeDeveloper:
+		return "developer"
+	}
+	return "unknown"
+}
+
+// Message_String turns message back to the raw form stored in queue
+func (m Message) String() string {
+	return string(m.Type) + m.ID
+}
+
+// Tracker holds tasks that are claimed from queue but not finished yet
+type Tracker struct {
+	sync.Mutex
+	tasks map[string]int
+}
+
+// Add will mark messages as claimed
+func (t *Tracker) Add(msgs ...Message) {
+	t.Lock()
+	defer t.Unlock()
+	if t.tasks == nil {
+		t.tasks = make(map[string]int)
+	}
+	for _, msg := range msgs {
+		t.tasks[msg.String()]++
+	}
+}
+
+// Done will mark message as finished
+func (t *Tracker) Done(msg Message) {
+	t.Lock()
+	defer t.Unlock()
+	key := msg.String()
+	if t.tasks[key] <= 1 {
+		delete(t.tasks, key)
+	} else {
+		t.tasks[key]--
+	}
+}
+
+// Pending returns raw ids of all unfinished tasks
+func (t *Tracker) Pending() (ids []string) {
+	t.Lock()
+	defer t.Unlock()
+	for id := range t.tasks {
+		ids = append(ids, id)
+	}
+	return
+}
+
+// Claimed tracks all tasks taken out of android_queue by this process
+var Claimed = new(Tracker)
+
+// Global config, loaded from flags, env and config file
+var Conf = DefaultConfig()
+
+// Global postgreSQL instance, connected according to Conf.DSN
+var Pg *pg.DB
 
 // SeenID will check whether given iTunesID is already in database
 func SeenID(apk string) bool {
 	var res int64
-	_, err := Pg.Query(&res, `SELECT count(id) FROM wdj WHERE id =`+apk)
+	_, err := Pg.QueryOne(pg.Scan(&res), `SELECT count(id) FROM wdj WHERE id = ?`, apk)
 	if err == nil && res == 1 {
 		return true
 	}
 	return false
 }
 
-// HandleWdj will fetch and save android application info from wandoujia by package name
+// HandleWdj will fetch and save android application info from wandoujia by package name,
+// sibling apps are filled from developer table, unknown vendor is put into queue
 func HandleWdj(apk string) error {
-	if android, err := wdj.Parse(apk); err != nil {
+	android, err := wdj.Parse(apk)
+	if err != nil {
+		if err == wdj.ErrParse {
+			ParseFailures.WithLabelValues("wdj").Inc()
+		}
 		return err
-	} else {
-		return android.Save(Pg)
 	}
+	ObserveFilled("wdj", android.Filled())
+	apps, known := DeveloperApps(android.Vendor)
+	android.SetSiblings(apps)
+	if err = SaveApp("wdj", apk, android.Permissions, android); err != nil {
+		return err
+	}
+	RowsUpserted.WithLabelValues("wdj").Inc()
+	if !known {
+		enqueueDeveloper(android.Vendor)
+	}
+	if _, err = HandleReviews("wdj", apk); err != nil {
+		log.Warnf("[REVIEW] wdj %s: %s", apk, err.Error())
+	}
+	return nil
 }
 
-// HandleSjqq will fetch and save android application info from yingyongbao by package name
+// HandleSjqq will fetch and save android application info from yingyongbao by package name,
+// comment and rating count are taken from review api. Developer of vendor is not enqueued,
+// as developers are crawled from wdj by vendor name shown there
 func HandleSjqq(apk string) error {
-	if android, err := sjqq.Parse(apk); err != nil {
+	android, err := sjqq.Parse(apk)
+	if err != nil {
+		if err == sjqq.ErrParse {
+			ParseFailures.WithLabelValues("sjqq").Inc()
+		}
 		return err
-	} else {
-		return android.Save(Pg)
 	}
+	if err = android.ScriptErr(); err != nil {
+		ScriptErrors.WithLabelValues("sjqq").Inc()
+		log.Warnf("[PARSE] sjqq %s appDetailData: %s", apk, err.Error())
+	}
+	ObserveFilled("sjqq", android.Filled())
+	if total, err := HandleReviews("sjqq", apk); err != nil {
+		log.Warnf("[REVIEW] sjqq %s: %s", apk, err.Error())
+	} else if total > 0 {
+		android.CommentCnt, android.RatingCnt = total, total
+	}
+	if err = SaveApp("sjqq", apk, android.Permissions, android); err != nil {
+		return err
+	}
+	RowsUpserted.WithLabelValues("sjqq").Inc()
+	return nil
 }
 
 // HandleApplesByKeyword find a series of app returned by iTunes Search API
-// and put them into queue
+// and put them into queue, ranks of found apps are saved into keyword_rank.
+// If too many result pages failed, apps are still enqueued but ranks are not saved
 func HandleKeyword(keyword string) error {
-	apks, err := wdj.Search(keyword)
-	if err != nil {
+	res, err := wdj.SearchWith(keyword, wdj.SearchOptions{
+		Concurrency: Conf.Search.Concurrency,
+		Retries:     Conf.Search.Retries,
+		MinCoverage: Conf.Search.MinCoverage,
+	})
+	if err != nil && err != wdj.ErrLowCoverage {
 		return err
 	}
+	SearchResults.Observe(float64(len(res.Items)))
+	SearchPageFailures.Add(float64(len(res.Failed)))
+	for _, e := range res.Failed {
+		log.Warnf("[SEARCH] keyword %s %s", keyword, e.Error())
+	}
 
-	if len(apks) == 0 {
-		return nil
+	if err == wdj.ErrLowCoverage {
+		log.Warnf("[SEARCH] keyword %s fetched %d of %d pages, ranks not saved", keyword, res.Pages-len(res.Failed), res.Pages)
+	} else if err = SaveRanks(keyword, "wdj", res.Items, time.Now()); err != nil {
+		return err
 	}
 
-	var sql bytes.Buffer
-	cnt := 0
-	sql.WriteString("INSERT INTO android_queue(id) VALUES ")
-	for i, apk := range apks {
-		if SeenID(apk) {
-			continue
-		}
-		cnt += 1
-		if i > 0 {
-			sql.WriteByte(',')
+	var ids []string
+	for _, apk := range wdj.Pkgs(res.Items) {
+		if !SeenID(apk) {
+			ids = append(ids, string(TypePackage)+apk)
 		}
-		sql.WriteString(`('!`)
-		sql.WriteString(apk)
-		sql.WriteString(`')`)
 	}
-	if cnt == 0 {
-		return nil
+	n, e := Enqueue(ids)
+	if e != nil {
+		return e
 	}
-	sql.WriteString("ON CONFLICT DO NOTHING;")
-	res, err := Pg.Exec(sql.String())
+	SearchEnqueued.Add(float64(n))
+	log.Infof("[SEARCH] keyword %s found %d, add %d", keyword, len(res.Items), n)
+	return err
+}
+
+// Find searches crawled apps in database and prints top results
+func Find(query string, limit int) error {
+	idx, err := search.Load(Pg)
 	if err != nil {
 		return err
 	}
-	log.Infof("[SEARCH] keyword %s found %d, add %d", keyword, len(apks), res.RowsAffected())
+	results := idx.Search(query, limit)
+	if len(results) == 0 {
+		fmt.Printf("no app matches %q in %d apps\n", query, idx.Len())
+		return nil
+	}
+	for i, r := range results {
+		fmt.Printf("%3d %8.3f %-40s %-20s %12d %s\n", i+1, r.Score, r.ID, r.Name, r.InstallCnt, strings.Join(r.Sources, ","))
+	}
 	return nil
 }
 
+// Requeue will put raw task ids back to `android_queue`, replaced in tests
+var Requeue = func(ids []string) error {
+	_, err := Enqueue(ids)
+	return err
+}
+
 // Producer will pull task from PostgreSQL table `android_queue`
-func Producer() <-chan Message {
+// It stops claiming new tasks and closes the channel when ctx is done
+func Producer(ctx context.Context) <-chan Message {
 	log.Info("[PROD] initializing...")
 	stmt, err := Pg.Prepare(`DELETE FROM android_queue WHERE id IN (SELECT id FROM android_queue LIMIT 100) RETURNING id;`)
 	if err != nil {
@@ -133,14 +280,30 @@ func Producer() <-chan Message {
 		return nil
 	}
 	c := make(chan Message)
-	go func(chan<- Message) {
+	go func(c chan<- Message) {
+		defer close(c)
+		defer stmt.Close()
 		sleep := time.Second
 		for {
+			select {
+			case <-ctx.Done():
+				log.Info("[PROD] stop claiming new tasks")
+				return
+			default:
+			}
+
 			var ids []string
 			_, err := stmt.Query(&ids)
-			if len(ids) == 0 {
-				log.Infof("[PROD] empty queue. sleep %d s", sleep/1e9)
-				time.Sleep(sleep)
+			if err != nil || len(ids) == 0 {
+				if err != nil {
+					log.Errorf("[PROD] claim tasks failed: %s", err.Error())
+				} else {
+					log.Infof("[PROD] empty queue. sleep %d s", sleep/1e9)
+				}
+				select {
+				case <-time.After(sleep):
+				case <-ctx.Done():
+				}
 				if sleep < 30*time.Second {
 					sleep *= 2
 				}
@@ -150,13 +313,23 @@ func Producer() <-chan Message {
 				sleep = time.Second
 			}
 
-			if err != nil {
-				fmt.Println(err.Error())
-				continue
-			}
+			var msgs []Message
 			for _, id := range ids {
 				if msg := NewMessage(id); msg.Valid() {
-					c <- msg
+					msgs = append(msgs, msg)
+				}
+			}
+			// claimed tasks are tracked until done, or put back on shutdown
+			Claimed.Add(msgs...)
+			for _, msg := range msgs {
+				TasksClaimed.WithLabelValues(msg.TypeName()).Inc()
+			}
+			for _, msg := range msgs {
+				select {
+				case c <- msg:
+				case <-ctx.Done():
+					log.Info("[PROD] stop claiming new tasks")
+					return
 				}
 			}
 		}
@@ -165,64 +338,163 @@ func Producer() <-chan Message {
 	return c
 }
 
-// Worker will handle incoming task
+// Worker will dispatch incoming task: package is fanned out to pools of
+// all enabled sources, keyword, category, chart and developer are handled directly
 func Worker(id int, c <-chan Message) {
 	log.Infof("[WORKER:%d] init", id)
+	name := fmt.Sprintf("worker:%d", id)
+	States.Set(name, "")
 	var err error
 	for msg := range c {
+		States.Set(name, msg.String())
 		switch msg.Type {
 		case TypePackage:
-			if err = HandleWdj(msg.ID); err != nil {
-				log.Errorf("[WORKER:%d] handle Package=%s @ wdj failed: %s", id, msg.ID, err.Error())
-			} else {
-				log.Infof("[WORKER:%d] done Package=%s @ wdj", id, msg.ID)
-			}
-
-			if err = HandleSjqq(msg.ID); err != nil {
-				log.Errorf("[WORKER:%d] handle Package=%s @ sjqq failed: %s", id, msg.ID, err.Error())
-			} else {
-				log.Infof("[WORKER:%d] done Package=%s @ sjqq", id, msg.ID)
+			t := NewTask(msg, len(Sources), func(t *Task) {
+				if t.Failed() > 0 {
+					log.Warnf("[TASK] Package=%s %s", t.Msg.ID, t.Report())
+				} else {
+					log.Infof("[TASK] Package=%s %s", t.Msg.ID, t.Report())
+				}
+				Claimed.Done(t.Msg)
+			})
+			for _, src := range Sources {
+				src.Submit(t)
 			}
 		case TypeKeywords:
+			SourceClaimed.WithLabelValues("keyword").Inc()
 			if err = HandleKeyword(msg.ID); err != nil {
 				log.Errorf("[WORKER:%d] handle Keyword=%s failed: %s", id, msg.ID, err.Error())
 			} else {
 				log.Infof("[WORKER:%d] done keyword=%s", id, msg.ID)
 			}
+			ObserveResult("keyword", err)
+			Claimed.Done(msg)
+		case TypeCategory, TypeChart:
+			SourceClaimed.WithLabelValues(msg.TypeName()).Inc()
+			if err = HandleList(msg); err != nil {
+				log.Errorf("[WORKER:%d] handle %s=%s failed: %s", id, msg.TypeName(), msg.ID, err.Error())
+			} else {
+				log.Infof("[WORKER:%d] done %s=%s", id, msg.TypeName(), msg.ID)
+			}
+			ObserveResult(msg.TypeName(), err)
+			Claimed.Done(msg)
+		case TypeDeveloper:
+			SourceClaimed.WithLabelValues("developer").Inc()
+			if err = HandleDeveloper(msg.ID); err != nil {
+				log.Errorf("[WORKER:%d] handle Developer=%s failed: %s", id, msg.ID, err.Error())
+			} else {
+				log.Infof("[WORKER:%d] done developer=%s", id, msg.ID)
+			}
+			ObserveResult("developer", err)
+			Claimed.Done(msg)
 		}
+		States.Set(name, "")
 	}
 	log.Infof("[WORK] %d finish", id)
 }
 
-// Run will start n worker and one producer.
-func Run(n int) {
+// Run will start source pools, n dispatch worker and one producer.
+// The returned channel is closed when all workers and pools exit after ctx is done
+func Run(ctx context.Context, n int) <-chan struct{} {
 	log.Infof("[RUN] init with %d worker...", n)
-	c := Producer()
+	done := make(chan struct{})
+	c := Producer(ctx)
+	if c == nil {
+		close(done)
+		return done
+	}
+
+	for _, src := range Sources {
+		src.Start()
+	}
+	var wg sync.WaitGroup
 	for i := 1; i <= n; i++ {
-		go Worker(i, c)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			Worker(i, c)
+		}(i)
 	}
+	go func() {
+		// dispatchers exit first, then pools drain their queues
+		wg.Wait()
+		for _, src := range Sources {
+			src.Stop()
+		}
+		close(done)
+	}()
+	return done
+}
+
+// Shutdown waits in-flight tasks for at most grace period, returns all
+// unfinished claimed tasks to queue, saves fill rate baselines and closes database connection.
+// Another signal on sig will skip the waiting.
+func Shutdown(done <-chan struct{}, sig <-chan os.Signal) {
+	log.Infof("[MAIN] waiting in-flight tasks, grace period %s", Conf.Grace)
+	select {
+	case <-done:
+		log.Info("[MAIN] all workers finished")
+	case <-time.After(Conf.Grace):
+		log.Warn("[MAIN] grace period exceeded")
+	case s := <-sig:
+		log.Warnf("[MAIN] receive %s again, stop waiting", s)
+	}
+
+	if ids := Claimed.Pending(); len(ids) > 0 {
+		if err := Requeue(ids); err != nil {
+			log.Errorf("[MAIN] requeue %d tasks failed: %s %v", len(ids), err.Error(), ids)
+		} else {
+			log.Infof("[MAIN] requeue %d unfinished tasks", len(ids))
+		}
+	}
+
+	CloseAlerts()
+	CloseArchives()
+	if Pg != nil {
+		if err := SaveBaselines(); err != nil {
+			log.Errorf("[DRIFT] save baselines failed: %s", err.Error())
+		}
+		if err := Pg.Close(); err != nil {
+			log.Errorf("[MAIN] close database failed: %s", err.Error())
+		}
+	}
+	log.Info("[MAIN] shutdown complete")
 }
 
 func main() {
-	log.SetLevel(log.InfoLevel)
+	conf, args, err := LoadConfig(os.Args[1:], os.Getenv)
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	} else if err != nil {
+		log.Fatalf("[MAIN] load config failed: %s", err.Error())
+	}
+	Conf = conf
+	level, _ := log.ParseLevel(Conf.LogLevel) // checked by Validate
+	log.SetLevel(level)
+	opts, err := Conf.PgOptions()
+	if err != nil {
+		log.Fatalf("[MAIN] invalid dsn: %s", err.Error())
+	}
+	Pg = pg.Connect(opts)
+	SetupSources(Conf)
+
+	if len(args) == 1 && strings.ToLower(args[0]) == "serve" {
+		ServeAPI(Conf.API)
+		os.Exit(0)
+	}
 
-	if len(os.Args) > 2 {
-		action, id := os.Args[1], os.Args[2]
+	if len(args) > 1 {
+		action, id := args[0], args[1]
 		action = strings.ToLower(action)
-		var err error
 		switch action {
 
 		case "a", "id", "pkg", "package", "apk":
-			if err = HandleWdj(id); err != nil {
-				log.Errorf("handle Package=%s @ wdj failed: %s", id, err.Error())
-			} else {
-				log.Infof("done Package=%s @ wdj", id)
-			}
-
-			if err = HandleSjqq(id); err != nil {
-				log.Errorf("handle Package=%s @ sjqq failed: %s", id, err.Error())
-			} else {
-				log.Infof("done Package=%s @ sjqq", id)
+			for _, src := range Sources {
+				if err = src.Handle(id); err != nil {
+					log.Errorf("handle Package=%s @ %s failed: %s", id, src.Name, err.Error())
+				} else {
+					log.Infof("done Package=%s @ %s", id, src.Name)
+				}
 			}
 		case "k", "key", "keyword", "keywords", "search":
 			if err := HandleKeyword(id); err != nil {
@@ -230,10 +502,73 @@ func main() {
 			} else {
 				log.Infof("done Keywords=%s", id)
 			}
+		case "dev", "developer", "vendor":
+			if err := HandleDeveloper(id); err != nil {
+				log.Errorf("handle Developer=%s failed: %s", id, err.Error())
+			} else {
+				log.Infof("done Developer=%s", id)
+			}
+		case "category", "chart":
+			msg := Message{TypeCategory, id}
+			if action == "chart" {
+				msg.Type = TypeChart
+			}
+			if !msg.Valid() {
+				log.Errorf("invalid %s %s, use source:name, e.g. wdj:5014 or wdj:hot", action, id)
+			} else if err := HandleList(msg); err != nil {
+				log.Errorf("handle %s=%s failed: %s", action, id, err.Error())
+			} else {
+				log.Infof("done %s=%s", action, id)
+			}
+		case "f", "find":
+			if err := Find(strings.Join(args[1:], " "), 20); err != nil {
+				log.Errorf("find %s failed: %s", id, err.Error())
+			}
+		case "reparse":
+			output := ""
+			if len(args) > 2 {
+				output = args[2]
+			}
+			if err := ReparseCommand(id, output); err != nil {
+				log.Errorf("reparse %s failed: %s", id, err.Error())
+			}
+		case "perm", "perms", "permissions":
+			if err := PermsCommand(id); err != nil {
+				log.Errorf("perms %s failed: %s", id, err.Error())
+			}
+		case "sentiment", "senti":
+			if err := Sentiment(id); err != nil {
+				log.Errorf("sentiment %s failed: %s", id, err.Error())
+			}
+		case "config":
+			if id == "print" {
+				fmt.Print(Conf.String())
+			}
 		}
 		os.Exit(0)
 	}
 
-	Run(5)
-	<-make(chan bool)
+	if err := LoadBaselines(); err != nil {
+		log.Errorf("[DRIFT] load baselines failed: %s", err.Error())
+	}
+	server := Serve(Conf.Listen)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := Run(ctx, Conf.Workers)
+	go Track(ctx, Conf.Tracked(), Conf.Interval)
+	go WatchSelectors(ctx, Conf.Selectors, 10*time.Second)
+	go KeepBaselines(ctx, 10*time.Minute)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case s := <-sig:
+		log.Infof("[MAIN] receive %s, stop claiming new tasks", s)
+	case <-done:
+		log.Warn("[MAIN] all workers exit unexpectedly")
+	}
+	cancel()
+	Shutdown(done, sig)
+	if server != nil {
+		server.Close()
+	}
 }
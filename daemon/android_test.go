@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"time"
+	"strings"
+	"testing"
+)
+
+func TestListMessage(t *testing.T) {
+	for _, c := range []struct {
+		raw   string
+		valid bool
+		name  string
+	}{
+		{"@wdj:5014_710", true, "category"},
+		{"^sjqq:hot", true, "chart"},
+		{"^gp:hot", false, "chart"},
+		{"@wdj:", false, "category"},
+		{"^hot", false, "chart"},
+	} {
+		msg := NewMessage(c.raw)
+		if msg.Valid() != c.valid || msg.TypeName() != c.name || msg.String() != c.raw {
+			t.Errorf("%s: valid = %v, type = %s", c.raw, msg.Valid(), msg.TypeName())
+		}
+	}
+	if source, list := NewMessage("@sjqq:2_147").List(); source != "sjqq" || list != "2_147" {
+		t.Errorf("list = %s %s", source, list)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := new(Tracker)
+	if ids := tr.Pending(); len(ids) != 0 {
+		t.Errorf("pending of empty tracker = %v", ids)
+	}
+	tr.Done(NewMessage("!unknown"))
+	mm, kw := NewMessage("!com.tencent.mm"), NewMessage("#微信")
+	// same package claimed twice stays pending until both are done
+	tr.Add(mm, kw, mm)
+	tr.Done(mm)
+	ids := tr.Pending()
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "!com.tencent.mm,#微信" {
+		t.Errorf("pending = %v", ids)
+	}
+	tr.Done(mm)
+	tr.Done(kw)
+	if ids = tr.Pending(); len(ids) != 0 {
+		t.Errorf("pending after done = %v", ids)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	claimed, requeue, grace := Claimed, Requeue, Conf.Grace
+	defer func() { Claimed, Requeue, Conf.Grace = claimed, requeue, grace }()
+
+	var requeued []string
+	Requeue = func(ids []string) error {
+		requeued = append(requeued, ids...)
+		return nil
+	}
+	Conf.Grace = 10 * time.Millisecond
+
+	// grace period exceeded: unfinished tasks are put back
+	Claimed = new(Tracker)
+	Claimed.Add(NewMessage("!com.tencent.mm"), NewMessage("@wdj:5014"), NewMessage("!com.eg.android.AlipayGphone"))
+	Claimed.Done(NewMessage("!com.eg.android.AlipayGphone"))
+	Shutdown(make(chan struct{}), nil)
+	sort.Strings(requeued)
+	if strings.Join(requeued, ",") != "!com.tencent.mm,@wdj:5014" {
+		t.Errorf("requeued = %v", requeued)
+	}
+
+	// second signal skips waiting
+	requeued = nil
+	Conf.Grace = time.Hour
+	Claimed = new(Tracker)
+	Claimed.Add(NewMessage("!com.tencent.mm"))
+	sig := make(chan os.Signal, 1)
+	sig <- os.Interrupt
+	Shutdown(make(chan struct{}), sig)
+	if len(requeued) != 1 {
+		t.Errorf("requeued after signal = %v", requeued)
+	}
+
+	// all finished: nothing to put back
+	requeued = nil
+	Claimed = new(Tracker)
+	done := make(chan struct{})
+	close(done)
+	Shutdown(done, nil)
+	if len(requeued) != 0 {
+		t.Errorf("requeued without pending = %v", requeued)
+	}
+}
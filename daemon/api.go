@@ -0,0 +1,407 @@
+package main
+
+import (
+	"os"
+	"fmt"
+	"time"
+	"errors"
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"net/http"
+	"os/signal"
+	"encoding/json"
+	"encoding/base64"
+)
+
+import (
+	"github.com/Vonng/go-android-search/perm"
+	"github.com/Vonng/go-android-search/review"
+	log "github.com/Sirupsen/logrus"
+)
+
+// App is a row of `android` parent table, which contains apps of all sources
+type App struct {
+	Source          string            `json:"source"`
+	ID              string            `json:"id" sql:",pk"`
+	Name            string            `json:"name"`
+	URL             string            `json:"url"`
+	Icon            string            `json:"icon"`
+	Link            string            `json:"link"`
+	Version         string            `json:"version"`
+	Vendor          string            `json:"vendor"`
+	Genre           string            `json:"genre"`
+	Tags            []string          `json:"tags" pg:",array"`
+	Categories      []string          `json:"categories" pg:",array"`
+	Price           int64             `json:"price"`
+	System          string            `json:"system"`
+	Platform        []string          `json:"platform" pg:",array"`
+	Permissions     []string          `json:"permissions" pg:",array"`
+	PermissionIDs   []string          `json:"permission_ids" pg:",array"`
+	UnmappedPerms   []string          `json:"unmapped_perms" pg:",array"`
+	Size            int64             `json:"size"`
+	Rating          int64             `json:"rating"`
+	RatingValue     float64           `json:"rating_value"`
+	RatingScale     string            `json:"rating_scale"`
+	RatingScore     float64           `json:"rating_score"`
+	RatingCnt       int64             `json:"rating_cnt"`
+	InstallCnt      int64             `json:"install_cnt"`
+	CommentCnt      int64             `json:"comment_cnt"`
+	Appkey          string            `json:"appkey"`
+	AppID           int64             `json:"app_id"`
+	ApkCode         int64             `json:"apk_code"`
+	Subtitle        string            `json:"subtitle"`
+	Commentary      string            `json:"commentary"`
+	Description     string            `json:"description"`
+	Reviews         []review.Review   `json:"reviews"`
+	News            []review.NewsItem `json:"news"`
+	Extra           string            `json:"extra"`
+	Screenshots     []string          `json:"screenshots" pg:",array"`
+	RelatedApps     []string          `json:"related_apps" pg:",array"`
+	SiblingApps     []string          `json:"sibling_apps" pg:",array"`
+	ReleaseNote     string            `json:"release_note"`
+	DescriptionHTML string            `json:"description_html"`
+	CommentaryHTML  string            `json:"commentary_html"`
+	ReleaseNoteHTML string            `json:"release_note_html"`
+	ReleaseTime     time.Time         `json:"release_time"`
+	CrawledTime     time.Time         `json:"crawled_time"`
+	Ratings         []SourceRating    `json:"ratings,omitempty" sql:"-"` // ratings of each source, merged records only
+	tableName       struct{}          `sql:"android"`
+}
+
+// SourceRating is the rating of an app in one source, ratings of different scales are only comparable by score
+type SourceRating struct {
+	Source string  `json:"source"`
+	Rating int64   `json:"rating"`
+	Value  float64 `json:"value"`
+	Scale  string  `json:"scale"`
+	Score  float64 `json:"score"`
+	Count  int64   `json:"count"`
+}
+
+// MergeApps combines records of same package from different sources.
+// Each field takes the first non-empty value in source order, while
+// install_cnt and comment_cnt are summed up, and source lists all sources.
+// When several sources are rated, rating_score is averaged weighted by rating_cnt of each source,
+// rating_cnt is their sum, and the scale-specific rating, rating_value and rating_scale are
+// cleared in favor of ratings, which keeps them per source
+func MergeApps(apps []*App) *App {
+	if len(apps) == 0 {
+		return nil
+	}
+	rank := make(map[string]int, len(SourceNames))
+	for i, name := range SourceNames {
+		rank[name] = i
+	}
+	sorted := append([]*App(nil), apps...)
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && rank[sorted[j].Source] < rank[sorted[j-1].Source]; j-- {
+			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+		}
+	}
+
+	merged := new(App)
+	dst := reflect.ValueOf(merged).Elem()
+	var sources []string
+	for _, app := range sorted {
+		src := reflect.ValueOf(app).Elem()
+		for i := 0; i < dst.NumField(); i++ {
+			if f := dst.Field(i); f.CanSet() && isZero(f) {
+				f.Set(src.Field(i))
+			}
+		}
+		sources = append(sources, app.Source)
+	}
+	merged.InstallCnt, merged.CommentCnt, merged.RatingCnt = 0, 0, 0
+	var ratings []SourceRating
+	for _, app := range sorted {
+		merged.InstallCnt += app.InstallCnt
+		merged.CommentCnt += app.CommentCnt
+		if app.RatingScale != "" {
+			merged.RatingCnt += app.RatingCnt
+			ratings = append(ratings, SourceRating{app.Source, app.Rating, app.RatingValue, app.RatingScale, app.RatingScore, app.RatingCnt})
+		}
+	}
+	merged.RatingScore = mergeScore(sorted)
+	if len(ratings) > 1 {
+		merged.Rating, merged.RatingValue, merged.RatingScale = 0, 0, ""
+		merged.Ratings = ratings
+	}
+	merged.Source = strings.Join(sources, ",")
+	return merged
+}
+
+// mergeScore averages rating_score of rated sources weighted by rating_cnt,
+// sources without rating count weigh the same when none of them has one
+func mergeScore(apps []*App) float64 {
+	var sum, weights, plain float64
+	var rated int
+	for _, app := range apps {
+		if app.RatingScale == "" {
+			continue
+		}
+		rated++
+		plain += app.RatingScore
+		sum += app.RatingScore * float64(app.RatingCnt)
+		weights += float64(app.RatingCnt)
+	}
+	switch {
+	case weights > 0:
+		return sum / weights
+	case rated > 0:
+		return plain / float64(rated)
+	}
+	return 0
+}
+
+// isZero tells whether field holds zero value or empty slice
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+/**************************************************************\
+* Cursor pagination
+***************************************************************/
+
+// Cursor marks position of last app of a page, ordered by install_cnt desc, id, source
+type Cursor struct {
+	InstallCnt int64  `json:"i"`
+	ID         string `json:"d"`
+	Source     string `json:"s"`
+}
+
+// Encode turns cursor into url safe token
+func (c *Cursor) Encode() string {
+	body, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+// DecodeCursor parses token made by Cursor.Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	body, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	c := new(Cursor)
+	if err = json.Unmarshal(body, c); err != nil || c.ID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// AppFilter holds conditions of listing apps
+type AppFilter struct {
+	Source     string
+	Genre      string
+	Vendor     string
+	Permission string
+	InstallMin *int64
+	InstallMax *int64
+	Limit      int
+	After      *Cursor
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 200
+)
+
+// ParseAppFilter builds filter from url query:
+// source, genre, vendor, permission, install_min, install_max, limit, cursor
+func ParseAppFilter(q map[string][]string) (f AppFilter, err error) {
+	get := func(key string) string {
+		if v := q[key]; len(v) > 0 {
+			return strings.TrimSpace(v[0])
+		}
+		return ""
+	}
+	f.Source, f.Genre, f.Vendor, f.Permission = get("source"), get("genre"), get("vendor"), get("permission")
+	if f.Source != "" && !isSource(f.Source) {
+		return f, fmt.Errorf("unknown source %q", f.Source)
+	}
+	for key, dst := range map[string]**int64{"install_min": &f.InstallMin, "install_max": &f.InstallMax} {
+		if v := get(key); v != "" {
+			n, e := strconv.ParseInt(v, 10, 64)
+			if e != nil {
+				return f, fmt.Errorf("invalid %s %q", key, v)
+			}
+			*dst = &n
+		}
+	}
+	f.Limit = defaultPageSize
+	if v := get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 1 {
+			return f, fmt.Errorf("invalid limit %q", v)
+		}
+		if f.Limit > maxPageSize {
+			f.Limit = maxPageSize
+		}
+	}
+	if v := get("cursor"); v != "" {
+		if f.After, err = DecodeCursor(v); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// ListApps returns a page of apps matching filter, and cursor of next page if any
+func ListApps(f AppFilter) (apps []*App, next *Cursor, err error) {
+	q := Pg.Model(&apps)
+	if f.Source != "" {
+		q = q.Where("source = ?", f.Source)
+	}
+	if f.Genre != "" {
+		q = q.Where("genre = ?", f.Genre)
+	}
+	if f.Vendor != "" {
+		q = q.Where("vendor = ?", f.Vendor)
+	}
+	if f.Permission != "" {
+		// a known description or manifest name matches every source, others match descriptions as is
+		if p, ok := perm.Lookup(f.Permission); ok {
+			q = q.Where("(? = ANY(permission_ids) OR ? = ANY(permissions))", p.Name, f.Permission)
+		} else {
+			q = q.Where("? = ANY(permissions)", f.Permission)
+		}
+	}
+	if f.InstallMin != nil {
+		q = q.Where("install_cnt >= ?", *f.InstallMin)
+	}
+	if f.InstallMax != nil {
+		q = q.Where("install_cnt <= ?", *f.InstallMax)
+	}
+	if c := f.After; c != nil {
+		q = q.Where("(coalesce(install_cnt, 0) < ? OR (coalesce(install_cnt, 0) = ? AND (id, source) > (?, ?)))",
+			c.InstallCnt, c.InstallCnt, c.ID, c.Source)
+	}
+	// fetch one more row to tell whether there is a next page
+	err = q.OrderExpr("coalesce(install_cnt, 0) DESC, id ASC, source ASC").Limit(f.Limit + 1).Select()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(apps) > f.Limit {
+		apps = apps[:f.Limit]
+		last := apps[len(apps)-1]
+		next = &Cursor{last.InstallCnt, last.ID, last.Source}
+	}
+	return apps, next, nil
+}
+
+// GetApp returns records of package from all sources
+func GetApp(pkg string) (apps []*App, err error) {
+	err = Pg.Model(&apps).Where("id = ?", pkg).Select()
+	return
+}
+
+/**************************************************************\
+* Read-only REST API
+***************************************************************/
+
+// APIMux serves read-only app metadata, it is served by `android serve`
+var APIMux = http.NewServeMux()
+
+func init() {
+	APIMux.HandleFunc("/apps", handleListApps)
+	APIMux.HandleFunc("/apps/", handleGetApp)
+	APIMux.HandleFunc("/healthz", handleHealth)
+	APIMux.HandleFunc("/readyz", handleReady)
+}
+
+// ServeAPI serves read-only api on addr until SIGINT or SIGTERM
+func ServeAPI(addr string) {
+	server := &http.Server{Addr: addr, Handler: APIMux}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		s := <-sig
+		log.Infof("[API] receive %s, shutting down", s)
+		ctx, cancel := context.WithTimeout(context.Background(), Conf.Grace)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	log.Infof("[API] listen on %s", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("[API] serve failed: %s", err.Error())
+	}
+	if err := Pg.Close(); err != nil {
+		log.Errorf("[API] close database failed: %s", err.Error())
+	}
+}
+
+// handleGetApp: GET /apps/{pkg} returns per-source records and merged record,
+// GET /apps/{pkg}?source=wdj returns record of that source only
+func handleGetApp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	pkg := strings.Trim(strings.TrimPrefix(r.URL.Path, "/apps/"), "/")
+	if pkg == "" {
+		handleListApps(w, r)
+		return
+	}
+	apps, err := GetApp(pkg)
+	if err != nil {
+		log.Errorf("[API] get app %s failed: %s", pkg, err.Error())
+		writeError(w, http.StatusInternalServerError, "query failed")
+		return
+	}
+
+	sources := make(map[string]*App, len(apps))
+	for _, app := range apps {
+		sources[app.Source] = app
+	}
+	if source := r.URL.Query().Get("source"); source != "" {
+		if app := sources[source]; app != nil {
+			writeJSON(w, http.StatusOK, app)
+		} else {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("%s not found in %s", pkg, source))
+		}
+		return
+	}
+	if len(apps) == 0 {
+		writeError(w, http.StatusNotFound, pkg+" not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      pkg,
+		"sources": sources,
+		"merged":  MergeApps(apps),
+	})
+}
+
+// handleListApps: GET /apps?genre=&vendor=&permission=&install_min=&install_max=&source=&limit=&cursor=
+// results are ordered by install_cnt desc, pass `next` of response as cursor to get next page
+func handleListApps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	f, err := ParseAppFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	apps, next, err := ListApps(f)
+	if err != nil {
+		log.Errorf("[API] list apps failed: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "query failed")
+		return
+	}
+	res := map[string]interface{}{"apps": apps, "next": nil}
+	if apps == nil {
+		res["apps"] = []*App{}
+	}
+	if next != nil {
+		res["next"] = next.Encode()
+	}
+	writeJSON(w, http.StatusOK, res)
+}
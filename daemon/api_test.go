@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestMergeApps(t *testing.T) {
+	wdj := &App{Source: "wdj", ID: "com.tencent.mm", Name: "微信", InstallCnt: 100, Tags: []string{"社交"}}
+	sjqq := &App{Source: "sjqq", ID: "com.tencent.mm", Name: "微信-腾讯", Vendor: "腾讯", InstallCnt: 50, CommentCnt: 3}
+	m := MergeApps([]*App{sjqq, wdj})
+	if m.Source != "wdj,sjqq" || m.Name != "微信" || m.Vendor != "腾讯" {
+		t.Errorf("merged = %+v", m)
+	}
+	if m.InstallCnt != 150 || m.CommentCnt != 3 || len(m.Tags) != 1 {
+		t.Errorf("merged counts = %d %d %v", m.InstallCnt, m.CommentCnt, m.Tags)
+	}
+	wdj.RatingValue, wdj.RatingScale, wdj.RatingScore, wdj.RatingCnt = 55, "percent", 0.55, 300
+	sjqq.RatingValue, sjqq.RatingScale, sjqq.RatingScore, sjqq.RatingCnt = 4.2, "stars", 0.8, 100
+	m = MergeApps([]*App{sjqq, wdj})
+	if m.RatingValue != 0 || m.RatingScale != "" || m.RatingCnt != 400 || m.RatingScore < 0.6124 || m.RatingScore > 0.6126 {
+		t.Errorf("merged rating = %v %s %v %d", m.RatingValue, m.RatingScale, m.RatingScore, m.RatingCnt)
+	}
+	if len(m.Ratings) != 2 || m.Ratings[0].Source != "wdj" || m.Ratings[0].Scale != "percent" || m.Ratings[1].Value != 4.2 {
+		t.Errorf("ratings by source = %+v", m.Ratings)
+	}
+	// a single rated source keeps its own scale, counts of unrated sources are left out
+	sjqq.RatingScale, sjqq.RatingCnt = "", 7
+	if m = MergeApps([]*App{sjqq, wdj}); m.RatingValue != 55 || m.RatingScale != "percent" || m.RatingCnt != 300 || m.Ratings != nil {
+		t.Errorf("merged rating of one rated source = %v %s %d %v", m.RatingValue, m.RatingScale, m.RatingCnt, m.Ratings)
+	}
+	sjqq.RatingScale = "stars"
+	wdj.RatingCnt, sjqq.RatingCnt = 0, 0
+	if m = MergeApps([]*App{sjqq, wdj}); m.RatingScore < 0.6749 || m.RatingScore > 0.6751 {
+		t.Errorf("merged score without counts = %v", m.RatingScore)
+	}
+	if MergeApps(nil) != nil {
+		t.Error("merge nothing should be nil")
+	}
+}
+
+func TestParseAppFilter(t *testing.T) {
+	c := &Cursor{1000, "com.tencent.mm", "wdj"}
+	q, _ := url.ParseQuery("genre=社交&install_min=10&limit=1000&cursor=" + c.Encode())
+	f, err := ParseAppFilter(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Genre != "社交" || *f.InstallMin != 10 || f.InstallMax != nil || f.Limit != maxPageSize {
+		t.Errorf("filter = %+v", f)
+	}
+	if f.After == nil || *f.After != *c {
+		t.Errorf("cursor = %+v, want %+v", f.After, c)
+	}
+
+	for _, bad := range []string{"source=gp", "install_max=1w", "limit=0", "cursor=xxx"} {
+		q, _ := url.ParseQuery(bad)
+		if _, err := ParseAppFilter(q); err == nil {
+			t.Errorf("%s should be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+import (
+	"github.com/Vonng/go-android-search/wdj"
+	"github.com/Vonng/go-android-search/sjqq"
+	log "github.com/Sirupsen/logrus"
+)
+
+// ListRank is position of an app in a category listing or top chart at crawled time
+type ListRank struct {
+	Source      string    `sql:",pk"`
+	Kind        string    `sql:",pk"` // category or chart
+	List        string    `sql:",pk"` // category id or chart name
+	ID          string    `sql:",pk"`
+	Rank        int       `sql:",notnull"`
+	Page        int       `sql:",notnull"`
+	Position    int       `sql:",notnull"`
+	CrawledTime time.Time `sql:",pk"`
+	tableName   struct{}  `sql:"chart_rank"`
+}
+
+// KnownChart reports whether chart name of source is builtin, see Charts in package of the source.
+// Only wdj hot is builtin, other charts are added once confirmed against a captured response
+func KnownChart(source, name string) bool {
+	switch source {
+	case "wdj":
+		_, ok := wdj.Charts[name]
+		return ok
+	case "sjqq":
+		_, ok := sjqq.Charts[name]
+		return ok
+	}
+	return false
+}
+
+// crawlList fetches category or chart of source, items are ordered by rank.
+// Items fetched before a failed page are returned along with error
+func crawlList(msg Message) (ranks []*ListRank, err error) {
+	source, list := msg.List()
+	kind := msg.TypeName()
+	add := func(pkg string, page, position, rank int) {
+		ranks = append(ranks, &ListRank{Source: source, Kind: kind, List: list, ID: pkg, Rank: rank, Page: page, Position: position})
+	}
+	switch source {
+	case "wdj":
+		var items []wdj.ListItem
+		if msg.Type == TypeCategory {
+			items, err = wdj.Category(list)
+		} else {
+			items, err = wdj.Chart(list)
+		}
+		for _, item := range items {
+			add(item.Pkg, item.Page, item.Position, item.Rank)
+		}
+	case "sjqq":
+		var items []sjqq.ListItem
+		if msg.Type == TypeCategory {
+			items, err = sjqq.Category(list)
+		} else {
+			items, err = sjqq.Chart(list)
+		}
+		for _, item := range items {
+			add(item.Pkg, item.Page, item.Position, item.Rank)
+		}
+	default:
+		err = fmt.Errorf("unknown source %q", source)
+	}
+	return
+}
+
+// HandleList crawls category or chart, saves a snapshot of positions into chart_rank
+// and puts newly seen packages into queue. If some pages failed, found packages
+// are still enqueued but the incomplete snapshot is not saved
+func HandleList(msg Message) error {
+	ranks, err := crawlList(msg)
+	if err != nil && len(ranks) == 0 {
+		return err
+	}
+	if err != nil {
+		log.Warnf("[LIST] %s %s incomplete, snapshot not saved: %s", msg.TypeName(), msg.ID, err.Error())
+	} else if err = SaveListRanks(ranks, time.Now()); err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, r := range ranks {
+		if !SeenID(r.ID) {
+			ids = append(ids, string(TypePackage)+r.ID)
+		}
+	}
+	n, e := Enqueue(ids)
+	if e != nil {
+		return e
+	}
+	log.Infof("[LIST] %s %s found %d, add %d", msg.TypeName(), msg.ID, len(ranks), n)
+	return err
+}
+
+// SaveListRanks writes a snapshot of list into chart_rank, all rows share crawled time t
+func SaveListRanks(ranks []*ListRank, t time.Time) error {
+	if len(ranks) == 0 {
+		return nil
+	}
+	for _, r := range ranks {
+		r.CrawledTime = t
+	}
+	res, err := Pg.Model(&ranks).OnConflict("DO NOTHING").Insert()
+	if err != nil {
+		return err
+	}
+	RowsUpserted.WithLabelValues("chart_rank").Add(float64(res.RowsAffected()))
+	return nil
+}
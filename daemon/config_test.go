@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"time"
+	"strings"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "android")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "android.yml")
+	ioutil.WriteFile(file, []byte(`
+dsn: postgres://file:file@db:5433/file
+workers: 3
+search:
+  retries: 3
+sources:
+  wdj:
+    workers: 2
+    rate_limit: 1.5
+`), 0644)
+
+	env := map[string]string{
+		"ANDROID_CONFIG":       file,
+		"ANDROID_WORKERS":      "7",
+		"ANDROID_SJQQ_TIMEOUT": "5s",
+		"ANDROID_KEYWORDS":     "王者荣耀, 地图,",
+		"ANDROID_ALERTS_APPS":  "com.tencent.mm,",
+	}
+	c, args, err := LoadConfig([]string{"-workers", "9", "-sources", "wdj", "config", "print"},
+		func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(args, " ") != "config print" {
+		t.Errorf("args = %v", args)
+	}
+	// flag > env > file > default
+	if c.Workers != 9 {
+		t.Errorf("workers = %d, want 9 from flag", c.Workers)
+	}
+	if c.Source("sjqq").Timeout != 5*time.Second {
+		t.Errorf("sjqq.timeout = %s, want 5s from env", c.Source("sjqq").Timeout)
+	}
+	if c.Source("wdj").Workers != 2 || c.Source("wdj").RateLimit != 1.5 {
+		t.Errorf("wdj = %+v, want settings from file", c.Source("wdj"))
+	}
+	if c.Source("wdj").Timeout != 30*time.Second {
+		t.Errorf("wdj.timeout = %s, want default 30s kept", c.Source("wdj").Timeout)
+	}
+	if strings.Join(c.Keywords, "|") != "王者荣耀|地图" || c.Interval != 6*time.Hour {
+		t.Errorf("keywords = %q every %s", c.Keywords, c.Interval)
+	}
+	if c.Search.Retries != 3 || c.Search.Concurrency != 4 {
+		t.Errorf("search = %+v, want retries from file and default concurrency", c.Search)
+	}
+	if strings.Join(c.Alerts.Apps, ",") != "com.tencent.mm" || strings.Join(c.Alerts.Levels, ",") != "dangerous" {
+		t.Errorf("alerts = %+v, want apps from env and default levels", c.Alerts)
+	}
+	if c.Listen != "127.0.0.1:8080" {
+		t.Errorf("listen = %s, want loopback by default", c.Listen)
+	}
+	if c.Grace != 30*time.Second {
+		t.Errorf("grace = %s, want default 30s", c.Grace)
+	}
+	if names := c.EnabledSources(); len(names) != 1 || names[0] != "wdj" {
+		t.Errorf("enabled sources = %v, want [wdj]", names)
+	}
+
+	opt, err := c.PgOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opt.Addr != "db:5433" || opt.User != "file" || opt.Password != "file" || opt.Database != "file" {
+		t.Errorf("pg options = %+v", opt)
+	}
+	if s := c.String(); strings.Contains(s, "file:file@") {
+		t.Errorf("password not masked: %s", s)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"-workers", "0"},
+		{"-sources", "wdj,mi"},
+		{"-dsn", "mysql://root@localhost/meta"},
+		{"-wdj.timeout", "3"},
+		{"-wdj.timeout", "-1s"},
+		{"-sjqq.rate_limit", "-2"},
+		{"-lists", "^sjqq:hot"},
+		{"-lists", "@wdj:5014,^wdj:rising"},
+		{"-alerts.notifiers", "log,mail"},
+		{"-alerts.levels", "high"},
+		{"-log_level", "verbose"},
+	} {
+		if _, _, err := LoadConfig(args, func(string) string { return "" }); err == nil {
+			t.Errorf("LoadConfig(%v) should fail", args)
+		}
+	}
+}
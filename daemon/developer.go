@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+import (
+	"github.com/go-pg/pg"
+	"github.com/Vonng/go-android-search/wdj"
+	log "github.com/Sirupsen/logrus"
+)
+
+// Developer is a row of developer table, vendors of all sources with same normalized name are merged
+type Developer struct {
+	ID          string            `sql:",pk"`        // normalized vendor name, see NormalizeVendor
+	Names       []string          `pg:",array"`      // vendor names shown in stores
+	SourceIDs   map[string]string `sql:"source_ids"` // developer id in each source, vendor name on wdj
+	Apps        []string          `pg:",array"`      // all apps of developer
+	AppCnt      int64             `sql:",notnull"`
+	InstallCnt  int64             `sql:",notnull"`
+	UpdatedTime time.Time
+	tableName   struct{} `sql:"developer"`
+}
+
+// company suffixes and business words stripped from vendor names, longer first
+var vendorSuffixes = []string{
+	"股份有限公司", "有限责任公司", "有限公司", "分公司", "公司", "集团", "工作室",
+	"信息技术", "网络技术", "计算机系统", "互动娱乐", "科技", "网络", "技术", "软件", "娱乐", "游戏",
+	"company limited", "co., ltd.", "co.,ltd.", "co., ltd", "co.,ltd", "co. ltd", "limited", "ltd.", "ltd",
+	"inc.", "inc", "corporation", "corp.", "corp", "company", "co.", "llc",
+	"technologies", "technology", "network", "software", "games", "studio",
+}
+
+// region prefixes stripped from vendor names
+var vendorPrefixes = []string{"北京", "上海", "深圳", "广州", "杭州", "成都", "南京", "武汉", "厦门", "珠海", "天津"}
+
+// NormalizeVendor turns vendor name into developer key, so that
+// `腾讯`, `腾讯科技（成都）有限公司` and `Tencent Technology (Shenzhen) Company Limited`
+// are keyed as `腾讯` and `tencent`
+func NormalizeVendor(name string) string {
+	// full width to half width, lower case, drop parenthesized parts
+	var b strings.Builder
+	depth := 0
+	for _, r := range name {
+		if r == '　' {
+			r = ' '
+		} else if r >= '！' && r <= '～' {
+			r -= 0xfee0
+		}
+		switch r {
+		case '(', '[':
+			depth++
+			continue
+		case ')', ']':
+			if depth > 0 {
+				depth--
+			}
+			continue
+		}
+		if depth == 0 {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	s := strings.Join(strings.Fields(b.String()), " ")
+
+	// strip suffixes and prefixes repeatedly, but never to empty
+	for changed := true; changed; {
+		changed = false
+		for _, suffix := range vendorSuffixes {
+			if t := strings.TrimSpace(strings.TrimSuffix(s, suffix)); t != s && t != "" {
+				s, changed = t, true
+			}
+		}
+		for _, prefix := range vendorPrefixes {
+			if t := strings.TrimPrefix(s, prefix); t != s && t != "" {
+				s, changed = t, true
+			}
+		}
+	}
+
+	// drop spaces and punctuation
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// developerSources lists sources whose developer pages are crawled. sjqq is absent until
+// its developer api is confirmed against a recorded response
+var developerSources = map[string]bool{"wdj": true}
+
+// crawlDeveloper fetches all apps of developer in source, id is vendor name shown in store
+func crawlDeveloper(source, id string) (apps []string, err error) {
+	switch source {
+	case "wdj":
+		var items []wdj.ListItem
+		items, err = wdj.Developer(id)
+		for _, item := range items {
+			apps = append(apps, item.Pkg)
+		}
+	default:
+		err = fmt.Errorf("developer pages of source %q are not crawled", source)
+	}
+	return
+}
+
+// HandleDeveloper crawls developer pages of vendor in enabled developerSources, updates developer table,
+// fills sibling_apps of its wdj apps and puts newly seen apps into queue
+func HandleDeveloper(name string) error {
+	dev := &Developer{ID: NormalizeVendor(name), Names: []string{name}, SourceIDs: make(map[string]string)}
+	if dev.ID == "" {
+		return fmt.Errorf("invalid vendor %q", name)
+	}
+
+	var errs []string
+	seen := make(map[string]bool)
+	for _, src := range Sources {
+		if !developerSources[src.Name] {
+			continue
+		}
+		apps, err := crawlDeveloper(src.Name, name)
+		if err != nil {
+			errs = append(errs, src.Name+": "+err.Error())
+		}
+		if len(apps) > 0 {
+			dev.SourceIDs[src.Name] = name
+		}
+		for _, apk := range apps {
+			if !seen[apk] {
+				seen[apk] = true
+				dev.Apps = append(dev.Apps, apk)
+			}
+		}
+	}
+	if len(dev.Apps) == 0 {
+		if len(errs) > 0 {
+			return errors.New(strings.Join(errs, "; "))
+		}
+		log.Infof("[DEV] developer %s has no app", name)
+		return nil
+	}
+
+	if err := SaveDeveloper(dev); err != nil {
+		return err
+	}
+	res, err := Pg.Exec(`UPDATE wdj SET sibling_apps = array_remove(?::TEXT[], id) WHERE id = ANY(?::TEXT[]);`,
+		pg.Array(dev.Apps), pg.Array(dev.Apps))
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, apk := range dev.Apps {
+		if !SeenID(apk) {
+			ids = append(ids, string(TypePackage)+apk)
+		}
+	}
+	n, err := Enqueue(ids)
+	if err != nil {
+		return err
+	}
+	log.Infof("[DEV] developer %s (%s) has %d apps, %d wdj siblings filled, add %d",
+		name, dev.ID, len(dev.Apps), res.RowsAffected(), n)
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// SaveDeveloper merges names, source ids and apps into developer table,
+// and refreshes app count and total installs from table android.
+// dev.Apps is updated to all known apps of developer
+func SaveDeveloper(dev *Developer) error {
+	dev.UpdatedTime = time.Now()
+	_, err := Pg.Model(dev).
+		OnConflict("(id) DO UPDATE").
+		Set("names = ARRAY(SELECT DISTINCT unnest(developer.names || EXCLUDED.names))").
+		Set("source_ids = developer.source_ids || EXCLUDED.source_ids").
+		Set("apps = ARRAY(SELECT DISTINCT unnest(developer.apps || EXCLUDED.apps))").
+		Set("updated_time = EXCLUDED.updated_time").
+		Returning("apps").
+		Insert()
+	if err != nil {
+		return err
+	}
+	RowsUpserted.WithLabelValues("developer").Inc()
+	_, err = Pg.Exec(`UPDATE developer d SET app_cnt = cardinality(d.apps),
+	install_cnt = coalesce((SELECT sum(install_cnt) FROM android WHERE id = ANY(d.apps)), 0) WHERE d.id = ?;`, dev.ID)
+	return err
+}
+
+// DeveloperApps returns all known apps of vendor, known is false if developer is not crawled yet.
+// Database errors are logged and treated as known, so that vendors are not enqueued repeatedly
+func DeveloperApps(vendor string) (apps []string, known bool) {
+	key := NormalizeVendor(vendor)
+	if key == "" {
+		return nil, true
+	}
+	_, err := Pg.QueryOne(pg.Scan(pg.Array(&apps)), `SELECT apps FROM developer WHERE id = ?;`, key)
+	if err == pg.ErrNoRows {
+		return nil, false
+	} else if err != nil {
+		log.Warnf("[DEV] query developer %s failed: %s", key, err.Error())
+	}
+	return apps, true
+}
+
+// enqueueDeveloper puts developer task of vendor into queue
+func enqueueDeveloper(vendor string) {
+	if _, err := Enqueue([]string{string(TypeDeveloper) + vendor}); err != nil {
+		log.Warnf("[DEV] enqueue developer %s failed: %s", vendor, err.Error())
+	}
+}
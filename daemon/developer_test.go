@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNormalizeVendor(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		want string
+	}{
+		{"腾讯", "腾讯"},
+		{"腾讯科技（成都）有限公司", "腾讯"},
+		{"腾讯科技(深圳)有限公司", "腾讯"},
+		{"高德软件有限公司", "高德"},
+		{"北京百度网讯科技有限公司", "百度网讯"},
+		{"Tencent Technology (Shenzhen) Company Limited", "tencent"},
+		{"ＮｅｔＥａｓｅ, Inc.", "netease"},
+		{"网易（杭州）网络有限公司", "网易"},
+		{"", ""},
+	} {
+		if got := NormalizeVendor(c.name); got != c.want {
+			t.Errorf("NormalizeVendor(%q) = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCrawlDeveloperSources(t *testing.T) {
+	if _, err := crawlDeveloper("sjqq", "腾讯"); err == nil {
+		t.Error("sjqq developer should not be crawled")
+	}
+}
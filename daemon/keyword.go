@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+	"context"
+)
+
+import (
+	"github.com/Vonng/go-android-search/wdj"
+	log "github.com/Sirupsen/logrus"
+)
+
+// KeywordRank is rank of an app in search results of keyword at crawled time
+type KeywordRank struct {
+	Keyword     string    `sql:",pk"`
+	Source      string    `sql:",pk"`
+	ID          string    `sql:",pk"`
+	Rank        int       `sql:",notnull"`
+	Page        int       `sql:",notnull"`
+	Position    int       `sql:",notnull"`
+	CrawledTime time.Time `sql:",pk"`
+	tableName   struct{}  `sql:"keyword_rank"`
+}
+
+// SaveRanks writes search results of keyword into keyword_rank,
+// all items share same crawled time so that each search forms a snapshot
+func SaveRanks(keyword, source string, items []wdj.SearchItem, t time.Time) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ranks := make([]*KeywordRank, len(items))
+	for i, item := range items {
+		ranks[i] = &KeywordRank{
+			Keyword:     keyword,
+			Source:      source,
+			ID:          item.Pkg,
+			Rank:        item.Rank,
+			Page:        item.Page,
+			Position:    item.Position,
+			CrawledTime: t,
+		}
+	}
+	res, err := Pg.Model(&ranks).OnConflict("DO NOTHING").Insert()
+	if err != nil {
+		return err
+	}
+	RowsUpserted.WithLabelValues("keyword_rank").Add(float64(res.RowsAffected()))
+	return nil
+}
+
+// Track puts tracked tasks into queue at once and then every interval,
+// until ctx is done. It does nothing if there is no task or interval is 0
+func Track(ctx context.Context, ids []string, interval time.Duration) {
+	if len(ids) == 0 || interval <= 0 {
+		return
+	}
+	log.Infof("[TRACK] track %d tasks every %s", len(ids), interval)
+	for {
+		if n, err := Enqueue(ids); err != nil {
+			log.Errorf("[TRACK] enqueue tracked tasks failed: %s", err.Error())
+		} else {
+			log.Infof("[TRACK] enqueue %d tracked tasks, %d inserted", len(ids), n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
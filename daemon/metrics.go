@@ -0,0 +1,300 @@
+package main
+
+import (
+	"net"
+	"math"
+	"time"
+	"context"
+	"strconv"
+	"net/http"
+)
+
+import (
+	"github.com/go-pg/pg"
+	"github.com/Vonng/go-android-search/wdj"
+	"github.com/Vonng/go-android-search/fill"
+	"github.com/Vonng/go-android-search/sjqq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/Sirupsen/logrus"
+)
+
+// Prometheus metrics exported on /metrics
+var (
+	// TasksClaimed counts tasks taken out of android_queue by type: package, keyword
+	TasksClaimed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "android_tasks_claimed_total",
+		Help: "Tasks claimed from android_queue by type.",
+	}, []string{"type"})
+
+	// SourceClaimed counts tasks submitted to each source
+	SourceClaimed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "android_source_tasks_claimed_total",
+		Help: "Tasks submitted to source, non-package tasks use their type as source.",
+	}, []string{"source"})
+
+	// SourceSucceeded counts tasks successfully handled by each source
+	SourceSucceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "android_source_tasks_succeeded_total",
+		Help: "Tasks succeeded by source.",
+	}, []string{"source"})
+
+	// SourceFailed counts failed tasks by source and error class
+	SourceFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "android_source_tasks_failed_total",
+		Help: "Tasks failed by source and error class: parse, coverage, timeout, network, database, other.",
+	}, []string{"source", "class"})
+
+	// ParseFailures counts pages that could not be parsed (ErrParse)
+	ParseFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "android_parse_failures_total",
+		Help: "Pages failed to parse by source.",
+	}, []string{"source"})
+
+	// ScriptErrors counts pages parsed with some values of embedded script data skipped
+	ScriptErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "android_script_errors_total",
+		Help: "Pages whose embedded script data had invalid values skipped, by source.",
+	}, []string{"source"})
+
+	// FetchDuration observes http round trip latency by host
+	FetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "android_fetch_duration_seconds",
+		Help:    "HTTP fetch latency by host.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"host"})
+
+	// FetchResponses counts http responses by host and status code, `error` for transport failures
+	FetchResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "android_fetch_responses_total",
+		Help: "HTTP responses by host and status code, code is `error` when request failed.",
+	}, []string{"host", "code"})
+
+	// RowsUpserted counts rows written by table
+	RowsUpserted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "android_rows_upserted_total",
+		Help: "Rows upserted by table.",
+	}, []string{"table"})
+
+	// SearchResults observes number of packages found by a keyword search
+	SearchResults = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "android_search_results",
+		Help:    "Packages found by keyword search.",
+		Buckets: []float64{0, 1, 5, 10, 20, 50, 100, 200, 500},
+	})
+
+	// SearchEnqueued counts new packages put into queue by keyword search
+	SearchEnqueued = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "android_search_enqueued_total",
+		Help: "New packages enqueued by keyword search.",
+	})
+
+	// SearchPageFailures counts search result pages failed after retries
+	SearchPageFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "android_search_page_failures_total",
+		Help: "Search result pages failed after retries.",
+	})
+
+	// FieldDrifts counts times fill rate of a field dropped below its baseline
+	FieldDrifts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "android_field_drifts_total",
+		Help: "Times fill rate of a parsed field dropped below its baseline, by source and field.",
+	}, []string{"source", "field"})
+
+	// QueueDepth reports number of tasks in android_queue on each scrape
+	QueueDepth = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "android_queue_depth",
+		Help: "Tasks waiting in android_queue.",
+	}, queueDepth)
+)
+
+func init() {
+	prometheus.MustRegister(
+		TasksClaimed, SourceClaimed, SourceSucceeded, SourceFailed, ParseFailures, ScriptErrors,
+		FetchDuration, FetchResponses, RowsUpserted, SearchResults, SearchEnqueued, SearchPageFailures, QueueDepth,
+		FieldDrifts, fillCollector{},
+	)
+}
+
+/**************************************************************\
+* Field fill rates
+***************************************************************/
+
+// FillRates tracks fill rate of every parsed field by source, rebuilt by SetupSources when drift options change
+var FillRates = fill.NewTracker(200, 0.3)
+
+var (
+	fillRateDesc = prometheus.NewDesc("android_field_fill_rate",
+		"Fill rate of parsed field over latest parses, by source and field.", []string{"source", "field"}, nil)
+	fillBaselineDesc = prometheus.NewDesc("android_field_fill_baseline",
+		"Long-term fill rate of parsed field, by source and field.", []string{"source", "field"}, nil)
+)
+
+// fillCollector exports FillRates on each scrape
+type fillCollector struct{}
+
+func (fillCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- fillRateDesc
+	ch <- fillBaselineDesc
+}
+
+func (fillCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, r := range FillRates.Rates() {
+		ch <- prometheus.MustNewConstMetric(fillRateDesc, prometheus.GaugeValue, r.Rate, r.Source, r.Field)
+		ch <- prometheus.MustNewConstMetric(fillBaselineDesc, prometheus.GaugeValue, r.Baseline, r.Source, r.Field)
+	}
+}
+
+// ObserveFilled records filled fields of a parsed app, an error is logged when
+// fill rate of a field drops below its baseline, which usually means a broken selector
+func ObserveFilled(source string, filled map[string]bool) {
+	for _, a := range FillRates.Observe(source, filled) {
+		if a.Recovered {
+			log.Infof("[DRIFT] %s field %s recovered, fill rate %.2f, baseline %.2f", a.Source, a.Field, a.Rate.Rate, a.Baseline)
+			continue
+		}
+		FieldDrifts.WithLabelValues(a.Source, a.Field).Inc()
+		log.Errorf("[DRIFT] %s field %s fill rate %.2f below baseline %.2f, check selector", a.Source, a.Field, a.Rate.Rate, a.Baseline)
+	}
+}
+
+// LoadBaselines seeds FillRates with baselines in table fill_baseline, so a selector
+// broken across a restart is still compared against the baseline of previous runs
+func LoadBaselines() error {
+	var rows []struct {
+		Source   string
+		Field    string
+		Baseline float64
+	}
+	if _, err := Pg.Query(&rows, `SELECT source, field, baseline FROM fill_baseline;`); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		FillRates.Seed(r.Source, r.Field, r.Baseline)
+	}
+	log.Infof("[DRIFT] load %d baselines", len(rows))
+	return nil
+}
+
+// SaveBaselines upserts baselines of fields past warmup into table fill_baseline,
+// fields not parsed enough in this run keep their saved baselines
+func SaveBaselines() error {
+	var sources, fields []string
+	var baselines []float64
+	var samples []int
+	for _, r := range FillRates.Rates() {
+		if r.Samples < FillRates.Warmup {
+			continue
+		}
+		sources, fields = append(sources, r.Source), append(fields, r.Field)
+		baselines, samples = append(baselines, r.Baseline), append(samples, r.Samples)
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+	_, err := Pg.Exec(`INSERT INTO fill_baseline(source, field, baseline, samples, updated_time)
+SELECT unnest(?::TEXT[]), unnest(?::TEXT[]), unnest(?::FLOAT8[]), unnest(?::BIGINT[]), now()
+ON CONFLICT (source, field) DO UPDATE SET baseline = EXCLUDED.baseline, samples = EXCLUDED.samples,
+  updated_time = EXCLUDED.updated_time;`, pg.Array(sources), pg.Array(fields), pg.Array(baselines), pg.Array(samples))
+	return err
+}
+
+// KeepBaselines saves baselines every interval until ctx is done, Shutdown saves the last ones
+func KeepBaselines(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		if err := SaveBaselines(); err != nil {
+			log.Errorf("[DRIFT] save baselines failed: %s", err.Error())
+		}
+	}
+}
+
+// queueDepth counts android_queue, NaN if database is unavailable
+func queueDepth() float64 {
+	if Pg == nil {
+		return math.NaN()
+	}
+	var n int64
+	if _, err := Pg.QueryOne(pg.Scan(&n), `SELECT count(*) FROM android_queue;`); err != nil {
+		log.Warnf("[METRICS] count android_queue failed: %s", err.Error())
+		return math.NaN()
+	}
+	return float64(n)
+}
+
+// ErrorClass classifies error for metrics label
+func ErrorClass(err error) string {
+	switch e := err.(type) {
+	case nil:
+		return ""
+	case pg.Error:
+		return "database"
+	case net.Error: // including *url.Error returned by http client
+		if e.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	}
+	if err == wdj.ErrParse || err == sjqq.ErrParse {
+		return "parse"
+	}
+	if err == wdj.ErrLowCoverage {
+		return "coverage"
+	}
+	if err == ErrSourceBusy {
+		return "busy"
+	}
+	return "other"
+}
+
+// ObserveResult records result of a task handled by source
+func ObserveResult(source string, err error) {
+	if err != nil {
+		SourceFailed.WithLabelValues(source, ErrorClass(err)).Inc()
+	} else {
+		SourceSucceeded.WithLabelValues(source).Inc()
+	}
+}
+
+// metricsTransport records latency and status code of each round trip
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.base.RoundTrip(req)
+	FetchDuration.WithLabelValues(req.URL.Host).Observe(time.Since(start).Seconds())
+	if err != nil {
+		FetchResponses.WithLabelValues(req.URL.Host, "error").Inc()
+	} else {
+		FetchResponses.WithLabelValues(req.URL.Host, strconv.Itoa(res.StatusCode)).Inc()
+	}
+	return res, err
+}
+
+// Mux serves daemon http endpoints
+var Mux = http.NewServeMux()
+
+func init() {
+	Mux.Handle("/metrics", promhttp.Handler())
+}
+
+// Serve starts http server of daemon on addr in background, returns nil if addr is empty
+func Serve(addr string) *http.Server {
+	if addr == "" {
+		return nil
+	}
+	server := &http.Server{Addr: addr, Handler: Mux}
+	go func() {
+		log.Infof("[HTTP] listen on %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Errorf("[HTTP] serve failed: %s", err.Error())
+		}
+	}()
+	return server
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"errors"
+	"net/url"
+	"testing"
+	"net/http"
+	"io/ioutil"
+)
+
+import (
+	"github.com/Vonng/go-android-search/wdj"
+	"github.com/Vonng/go-android-search/warc"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorClass(t *testing.T) {
+	for _, c := range []struct {
+		err   error
+		class string
+	}{
+		{nil, ""},
+		{&url.Error{Op: "Get", URL: "http://www.wandoujia.com", Err: timeoutError{}}, "timeout"},
+		{&url.Error{Op: "Get", URL: "http://www.wandoujia.com", Err: errors.New("connection refused")}, "network"},
+		{wdj.ErrParse, "parse"},
+		{wdj.ErrLowCoverage, "coverage"},
+		{errors.New("boom"), "other"},
+	} {
+		if class := ErrorClass(c.err); class != c.class {
+			t.Errorf("ErrorClass(%v) = %q, want %q", c.err, class, c.class)
+		}
+	}
+}
+
+func TestNewClientTransport(t *testing.T) {
+	client := NewClient(&SourceConfig{RateLimit: 1}, nil)
+	limited, ok := client.Transport.(*limitedTransport)
+	if !ok {
+		t.Fatalf("outermost transport = %T, want limiter", client.Transport)
+	}
+	if m, ok := limited.base.(*metricsTransport); !ok || m.base != http.DefaultTransport {
+		t.Errorf("transport under limiter = %T, want metrics", limited.base)
+	}
+
+	// archiving wraps metrics, so fetch duration excludes writing warc files
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w, err := warc.NewWriter(dir, "wdj", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	limited = NewClient(&SourceConfig{}, w).Transport.(*limitedTransport)
+	archived, ok := limited.base.(*warc.Transport)
+	if !ok {
+		t.Fatalf("transport under limiter = %T, want archive", limited.base)
+	}
+	if _, ok := archived.Base.(*metricsTransport); !ok {
+		t.Errorf("transport under archive = %T, want metrics", archived.Base)
+	}
+}
@@ -0,0 +1,79 @@
+---------------------------------------------------------------
+-- Migrations of existing data, each statement can be run repeatedly
+-- psql meta meta < migrate.sql
+---------------------------------------------------------------
+
+-----------------------------------------
+-- reviews & news: tuples to objects
+-- reviews `[<user>,"YYYYMMDD",<content>]` => `{"source","app_id","user","date","content"}`
+-- news    `[<title>,<url>,<source>]`      => `{"title","url","source"}`
+-- android is parent of wdj and sjqq, updating it updates both
+-----------------------------------------
+UPDATE android a SET reviews = (
+  SELECT jsonb_agg(CASE jsonb_typeof(e)
+                   WHEN 'array' THEN jsonb_build_object(
+                       'source', a.source,
+                       'app_id', a.id,
+                       'user', e ->> 0,
+                       'date', to_jsonb(to_date(e ->> 1, 'YYYYMMDD') :: TIMESTAMPTZ),
+                       'content', e ->> 2)
+                   ELSE e END ORDER BY i)
+  FROM jsonb_array_elements(a.reviews) WITH ORDINALITY AS t(e, i))
+WHERE jsonb_typeof(a.reviews) = 'array'
+      AND EXISTS(SELECT 1 FROM jsonb_array_elements(a.reviews) e WHERE jsonb_typeof(e) = 'array');
+
+UPDATE android a SET news = (
+  SELECT jsonb_agg(CASE jsonb_typeof(e)
+                   WHEN 'array' THEN jsonb_build_object('title', e ->> 0, 'url', e ->> 1, 'source', e ->> 2)
+                   ELSE e END ORDER BY i)
+  FROM jsonb_array_elements(a.news) WITH ORDINALITY AS t(e, i))
+WHERE jsonb_typeof(a.news) = 'array'
+      AND EXISTS(SELECT 1 FROM jsonb_array_elements(a.news) e WHERE jsonb_typeof(e) = 'array');
+
+
+-----------------------------------------
+-- rich text: sanitized html beside plain text
+-- existing description/commentary/release_note may still hold raw html, run `android reparse` on archives to refill
+-----------------------------------------
+ALTER TABLE android ADD COLUMN IF NOT EXISTS description_html TEXT;
+ALTER TABLE android ADD COLUMN IF NOT EXISTS commentary_html TEXT;
+ALTER TABLE android ADD COLUMN IF NOT EXISTS release_note_html TEXT;
+
+
+-----------------------------------------
+-- rating scale: raw value, scale, normalized score and count beside legacy rating
+-- legacy rating is 好评率 on wdj, and 5-star average * 20 on sjqq (truncated)
+-----------------------------------------
+ALTER TABLE android ADD COLUMN IF NOT EXISTS rating_value DOUBLE PRECISION;
+ALTER TABLE android ADD COLUMN IF NOT EXISTS rating_scale TEXT;
+ALTER TABLE android ADD COLUMN IF NOT EXISTS rating_score DOUBLE PRECISION;
+ALTER TABLE android ADD COLUMN IF NOT EXISTS rating_cnt BIGINT;
+
+UPDATE wdj SET rating_value = rating, rating_scale = 'percent', rating_score = rating / 100.0,
+  rating_cnt = coalesce(rating_cnt, comment_cnt)
+WHERE rating > 0 AND rating_scale IS NULL;
+
+UPDATE sjqq SET rating_value = rating / 20.0, rating_scale = 'stars',
+  rating_score = greatest(0, least(1, (rating / 20.0 - 1) / 4)), rating_cnt = coalesce(rating_cnt, comment_cnt)
+WHERE rating > 0 AND rating_scale IS NULL;
+
+
+-----------------------------------------
+-- permission ids: manifest names mapped from descriptions by package perm
+-- run `android perms remap` afterwards to fill existing rows
+-----------------------------------------
+ALTER TABLE android ADD COLUMN IF NOT EXISTS permission_ids TEXT [];
+ALTER TABLE android ADD COLUMN IF NOT EXISTS unmapped_perms TEXT [];
+
+
+-----------------------------------------
+-- fill baseline: long-term fill rate of parsed fields, kept across restarts
+-----------------------------------------
+CREATE TABLE IF NOT EXISTS fill_baseline (
+  source       TEXT NOT NULL,
+  field        TEXT NOT NULL,
+  baseline     DOUBLE PRECISION NOT NULL,
+  samples      BIGINT NOT NULL,
+  updated_time TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (source, field)
+);
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+import (
+	"github.com/go-pg/pg"
+	"github.com/Vonng/go-android-search/perm"
+	log "github.com/Sirupsen/logrus"
+)
+
+// alertQueue is how many batches of permission changes may wait for notifiers, further ones are dropped
+const alertQueue = 1000
+
+// permission change alerts, rebuilt by SetupSources
+var (
+	PermFilter   = new(perm.Filter)
+	PermNotifier *perm.AsyncNotifier
+)
+
+// SetupAlerts builds filter and notifier of permission change alerts, `log` notifier writes warnings.
+// Notifiers run in background so that a slow webhook never blocks source workers,
+// changes queued before a reload are still sent by previous notifiers
+func SetupAlerts(c *Config) {
+	PermFilter = c.Alerts.Filter()
+	n, err := c.Alerts.Notifier()
+	if err != nil {
+		log.Errorf("[PERM] invalid alerts: %s", err.Error())
+	}
+	if ns, ok := n.(perm.Notifiers); ok {
+		for i := range ns {
+			if _, ok := ns[i].(perm.LogNotifier); ok {
+				ns[i] = perm.LogNotifier{Printf: log.Warnf}
+			}
+		}
+	}
+	old := PermNotifier
+	PermNotifier = nil
+	if n != nil {
+		PermNotifier = perm.NewAsyncNotifier(n, alertQueue, func(changes []perm.Change, err error) {
+			log.Warnf("[PERM] notify %d permission changes failed: %s", len(changes), err.Error())
+		})
+	}
+	if old != nil {
+		go old.Close()
+	}
+}
+
+// CloseAlerts waits for queued permission change alerts to be sent
+func CloseAlerts() {
+	if PermNotifier != nil {
+		PermNotifier.Close()
+	}
+}
+
+// SaveApp saves app parsed from source, permissions are compared with those of stored row,
+// changes are recorded into perm_change and alerted. Nothing is compared for a new app,
+// or when no permission is parsed, which is more likely a broken selector than an app without permission.
+// The stored row is locked until the app and its changes are saved, so concurrent crawls of
+// the same app never compare against a stale row
+func SaveApp(source, id string, permissions []string, app ParsedApp) error {
+	var changes []perm.Change
+	err := Pg.RunInTransaction(func(tx *pg.Tx) error {
+		var old []string
+		_, err := tx.QueryOne(pg.Scan(pg.Array(&old)),
+			`SELECT permissions FROM android WHERE source = ? AND id = ? FOR UPDATE;`, source, id)
+		known := err == nil
+		if err != nil && err != pg.ErrNoRows {
+			return err
+		}
+		if err = app.Save(tx); err != nil {
+			return err
+		}
+		if !known || len(permissions) == 0 {
+			return nil
+		}
+		changes = perm.Diff(source, id, old, permissions, time.Now())
+		return perm.SaveChanges(tx, changes)
+	})
+	if err != nil {
+		return err
+	}
+	if matched := PermFilter.Apply(changes); len(matched) > 0 && PermNotifier != nil {
+		if err = PermNotifier.Notify(matched); err != nil {
+			log.Warnf("[PERM] alert %d permission changes of %s %s dropped: %s", len(matched), source, id, err.Error())
+		}
+	}
+	return nil
+}
+
+// remapBatch is how many rows are loaded at a time by RemapPermissions
+const remapBatch = 1000
+
+// PermsCommand serves `android perms <apk|unmapped|remap>`
+func PermsCommand(arg string) error {
+	switch arg {
+	case "unmapped":
+		return PrintUnmapped(100)
+	case "remap":
+		n, err := RemapPermissions()
+		if err == nil {
+			log.Infof("[PERM] remapped permissions of %d rows", n)
+		}
+		return err
+	}
+	return PrintPermissions(arg)
+}
+
+// PrintPermissions prints manifest permissions of apk in every source with protection level,
+// followed by descriptions that are not mapped yet
+func PrintPermissions(apk string) error {
+	apps, err := GetApp(apk)
+	if err != nil {
+		return err
+	}
+	if len(apps) == 0 {
+		fmt.Printf("%s not found\n", apk)
+	}
+	for _, app := range apps {
+		fmt.Printf("%s %s: %d mapped, %d unmapped\n", app.Source, app.ID, len(app.PermissionIDs), len(app.UnmappedPerms))
+		for _, name := range app.PermissionIDs {
+			level := "unknown"
+			if p, ok := perm.Get(name); ok {
+				level = p.Level
+			}
+			fmt.Printf("  %-10s %s\n", level, name)
+		}
+		for _, desc := range app.UnmappedPerms {
+			fmt.Printf("  %-10s %s\n", "?", desc)
+		}
+	}
+	return nil
+}
+
+// PrintUnmapped prints the most common permission descriptions that are not in the mapping table,
+// with how many apps of which sources declare them
+func PrintUnmapped(limit int) error {
+	var rows []struct {
+		Description string
+		Apps        int64
+		Sources     string
+	}
+	_, err := Pg.Query(&rows, `SELECT u AS description, count(*) AS apps, string_agg(DISTINCT source, ',') AS sources
+FROM android, unnest(unmapped_perms) u GROUP BY u ORDER BY 2 DESC, 1 LIMIT ?;`, limit)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		fmt.Printf("%8d %-10s %s\n", row.Apps, row.Sources, row.Description)
+	}
+	return nil
+}
+
+// RemapPermissions resolves stored permission descriptions again with current mapping table,
+// rows whose permission_ids or unmapped_perms changed are updated, returns number of updated rows
+func RemapPermissions() (updated int, err error) {
+	var last struct{ ID, Source string }
+	for {
+		var apps []*App
+		err = Pg.Model(&apps).Column("source", "id", "permissions", "permission_ids", "unmapped_perms").
+			Where("(id, source) > (?, ?)", last.ID, last.Source).
+			OrderExpr("id ASC, source ASC").Limit(remapBatch).Select()
+		if err != nil || len(apps) == 0 {
+			return
+		}
+		for _, app := range apps {
+			ids, unmapped := perm.Resolve(app.Permissions)
+			if sameList(ids, app.PermissionIDs) && sameList(unmapped, app.UnmappedPerms) {
+				continue
+			}
+			_, err = Pg.Exec(`UPDATE android SET permission_ids = ?, unmapped_perms = ? WHERE source = ? AND id = ?;`,
+				pg.Array(ids), pg.Array(unmapped), app.Source, app.ID)
+			if err != nil {
+				return
+			}
+			updated++
+		}
+		last.ID, last.Source = apps[len(apps)-1].ID, apps[len(apps)-1].Source
+	}
+}
+
+func sameList(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"io"
+	"os"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"bufio"
+	"strings"
+	"encoding/json"
+	"path/filepath"
+)
+
+import (
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vonng/go-android-search/wdj"
+	"github.com/Vonng/go-android-search/sjqq"
+	"github.com/Vonng/go-android-search/warc"
+	log "github.com/Sirupsen/logrus"
+)
+
+// ParsedApp is an app parsed from a saved detail page of any source
+type ParsedApp interface {
+	Save(db orm.DB) error
+}
+
+// reparser parses saved detail pages of a source
+type reparser struct {
+	Source string
+	Prefix string // detail page url prefix, matched against warc target uri
+	File   func(filename string) (ParsedApp, error)
+	Doc    func(doc *goquery.Document) (ParsedApp, error)
+}
+
+var reparsers = []reparser{
+	{"wdj", wdj.AppPagePrefix, func(filename string) (ParsedApp, error) {
+		app, err := wdj.ParseFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return app, nil
+	}, func(doc *goquery.Document) (ParsedApp, error) {
+		app := new(wdj.App)
+		if err := app.Parse(doc); err != nil {
+			return nil, err
+		}
+		return app, nil
+	}},
+	{"sjqq", sjqq.AppPagePrefix, func(filename string) (ParsedApp, error) {
+		app, err := sjqq.ParseFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return app, nil
+	}, func(doc *goquery.Document) (ParsedApp, error) {
+		app := new(sjqq.App)
+		if err := app.Parse(doc); err != nil {
+			return nil, err
+		}
+		return app, nil
+	}},
+}
+
+// reparserOfPath finds source of a saved page by its path, a path element
+// or file name prefix must be source name, e.g. wdj/sample/com.tencent.mm.html
+func reparserOfPath(path string) *reparser {
+	for _, elem := range strings.Split(filepath.ToSlash(path), "/") {
+		for i := range reparsers {
+			if elem == reparsers[i].Source || strings.HasPrefix(elem, reparsers[i].Source+"-") {
+				return &reparsers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// reparserOfURI finds source of an archived page by its url, nil if it's not a detail page.
+// The url must be prefix followed by a package name only, other pages sharing the prefix,
+// like wdj reviews `/apps/<id>/comment2`, are not detail pages
+func reparserOfURI(uri string) *reparser {
+	for i := range reparsers {
+		if id := strings.TrimPrefix(uri, reparsers[i].Prefix); id != uri && isPackageName(id) {
+			return &reparsers[i]
+		}
+	}
+	return nil
+}
+
+// isPackageName tells whether s looks like an android package name, e.g. com.tencent.mm
+func isPackageName(s string) bool {
+	if s == "" || s[0] == '.' || s[len(s)-1] == '.' || !strings.Contains(s, ".") {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// reparseJob parses one saved page
+type reparseJob struct {
+	Name    string    // file path, or warc file and target uri
+	Crawled time.Time // when page was fetched: WARC-Date, or modification time of html file
+	Parse   func() (ParsedApp, error)
+}
+
+// ReparseSummary counts results of a reparse run
+type ReparseSummary struct {
+	Total    int
+	Parsed   map[string]int    // parsed apps by source
+	Stale    int               // parsed apps not saved, as stored rows are crawled later
+	Failures map[string]string // error by page
+}
+
+// reparseJobs walks path and sends jobs of all saved detail pages: html files, and
+// response records of detail pages in warc files. Errors reading files are reported as failed jobs
+func reparseJobs(path string, jobs chan<- reparseJob) error {
+	defer close(jobs)
+	return filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch name := info.Name(); {
+		case info.IsDir():
+		case strings.HasSuffix(name, ".warc") || strings.HasSuffix(name, ".warc.gz"):
+			err = warc.Responses(file, func(rec *warc.Record) error {
+				rp := reparserOfURI(rec.TargetURI)
+				if rp == nil {
+					return nil
+				}
+				jobs <- reparseJob{file + " " + rec.TargetURI, rec.Date, func() (ParsedApp, error) {
+					res, err := rec.Response()
+					if err != nil {
+						return nil, err
+					}
+					doc, err := goquery.NewDocumentFromResponse(res)
+					if err != nil {
+						return nil, err
+					}
+					return rp.Doc(doc)
+				}}
+				return nil
+			})
+			if err != nil {
+				jobs <- reparseJob{file, info.ModTime(), func() (ParsedApp, error) { return nil, err }}
+			}
+		case strings.HasSuffix(name, ".html") || strings.HasSuffix(name, ".htm"):
+			rp := reparserOfPath(file)
+			if rp == nil {
+				jobs <- reparseJob{file, info.ModTime(), func() (ParsedApp, error) {
+					return nil, fmt.Errorf("unknown source, put page under a directory named by source")
+				}}
+				return nil
+			}
+			jobs <- reparseJob{file, info.ModTime(), func() (ParsedApp, error) { return rp.File(file) }}
+		}
+		return nil
+	})
+}
+
+// Reparse parses all saved detail pages under path with workers concurrently.
+// crawled_time of parsed apps is when page was fetched. Parsed apps are written into out
+// as JSON lines, or saved into database if out is nil, unless stored row is crawled later
+func Reparse(path string, workers int, out io.Writer) (*ReparseSummary, error) {
+	if workers <= 0 {
+		workers = 1
+	}
+	jobs := make(chan reparseJob, workers)
+	walkErr := make(chan error, 1)
+	go func() { walkErr <- reparseJobs(path, jobs) }()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sum := &ReparseSummary{Parsed: make(map[string]int), Failures: make(map[string]string)}
+	var enc *json.Encoder
+	if out != nil {
+		enc = json.NewEncoder(out)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				app, err := job.Parse()
+				saved := true
+				if err == nil {
+					setCrawledTime(app, job.Crawled)
+					if enc == nil {
+						saved, err = saveIfNewer(app, job.Crawled)
+					}
+				}
+				mu.Lock()
+				sum.Total++
+				if err == nil && enc != nil {
+					err = enc.Encode(app)
+				}
+				if err != nil {
+					sum.Failures[job.Name] = err.Error()
+				} else {
+					sum.Parsed[sourceOf(app)]++
+				}
+				if !saved {
+					sum.Stale++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return sum, <-walkErr
+}
+
+// setCrawledTime overwrites crawled time of parsed app with fetch time of page
+func setCrawledTime(app ParsedApp, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	switch a := app.(type) {
+	case *wdj.App:
+		a.CrawledTime = t
+	case *sjqq.App:
+		a.CrawledTime = t
+	}
+}
+
+// saveIfNewer saves parsed app unless its stored row was crawled after t, returns whether it's saved
+func saveIfNewer(app ParsedApp, t time.Time) (bool, error) {
+	var id string
+	switch a := app.(type) {
+	case *wdj.App:
+		id = a.ID
+	case *sjqq.App:
+		id = a.ID
+	}
+	var newer bool
+	_, err := Pg.QueryOne(pg.Scan(&newer), `SELECT EXISTS(SELECT 1 FROM android WHERE source = ? AND id = ? AND crawled_time > ?);`,
+		sourceOf(app), id, t)
+	if err != nil || newer {
+		return false, err
+	}
+	return true, app.Save(Pg)
+}
+
+// sourceOf returns source name of parsed app
+func sourceOf(app ParsedApp) string {
+	switch app.(type) {
+	case *wdj.App:
+		return "wdj"
+	case *sjqq.App:
+		return "sjqq"
+	}
+	return "unknown"
+}
+
+// Print writes summary with failures grouped by error
+func (s *ReparseSummary) Print(w io.Writer) {
+	parsed := 0
+	for _, n := range s.Parsed {
+		parsed += n
+	}
+	fmt.Fprintf(w, "pages %d, parsed %d, failed %d", s.Total, parsed, len(s.Failures))
+	if s.Stale > 0 {
+		fmt.Fprintf(w, ", %d not saved as stored rows are newer", s.Stale)
+	}
+	fmt.Fprintln(w)
+	for _, name := range SourceNames {
+		if n := s.Parsed[name]; n > 0 {
+			fmt.Fprintf(w, "  %-6s %d\n", name, n)
+		}
+	}
+	if len(s.Failures) == 0 {
+		return
+	}
+
+	byErr := make(map[string][]string)
+	for page, err := range s.Failures {
+		byErr[err] = append(byErr[err], page)
+	}
+	errs := make([]string, 0, len(byErr))
+	for err := range byErr {
+		errs = append(errs, err)
+	}
+	sort.Slice(errs, func(i, j int) bool { return len(byErr[errs[i]]) > len(byErr[errs[j]]) })
+	fmt.Fprintln(w, "failures:")
+	for _, err := range errs {
+		pages := byErr[err]
+		sort.Strings(pages)
+		fmt.Fprintf(w, "  %5d %s\n", len(pages), err)
+		for i, page := range pages {
+			if i == 5 {
+				fmt.Fprintf(w, "        ... %d more\n", len(pages)-i)
+				break
+			}
+			fmt.Fprintf(w, "        %s\n", page)
+		}
+	}
+}
+
+// ReparseCommand runs reparse from command line, results go to database,
+// or to JSON lines file if output is not empty
+func ReparseCommand(path, output string) error {
+	var out io.Writer
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w := bufio.NewWriter(f)
+		defer w.Flush()
+		out = w
+	}
+	sum, err := Reparse(path, Conf.Workers, out)
+	sum.Print(os.Stdout)
+	if err != nil {
+		return err
+	}
+	log.Infof("[REPARSE] %s: %d pages, %d failed", path, sum.Total, len(sum.Failures))
+	return nil
+}
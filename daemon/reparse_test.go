@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"time"
+	"bytes"
+	"strings"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+import (
+	"github.com/Vonng/go-android-search/wdj"
+	"github.com/Vonng/go-android-search/warc"
+)
+
+func TestReparse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reparse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// saved pages of both sources, and a page of unknown source
+	for _, src := range []string{"wdj", "sjqq"} {
+		files, _ := filepath.Glob(filepath.Join("..", src, "sample", "*.html"))
+		os.MkdirAll(filepath.Join(dir, src), 0755)
+		for _, file := range files {
+			body, _ := ioutil.ReadFile(file)
+			ioutil.WriteFile(filepath.Join(dir, src, filepath.Base(file)), body, 0644)
+		}
+	}
+	ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte("<html></html>"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "wdj", "broken.html"), []byte("<html></html>"), 0644)
+
+	// archived detail page and a non-detail page
+	// archived detail page, a review page sharing its prefix and a non-detail page
+	page, _ := ioutil.ReadFile("../wdj/sample/com.tencent.mm.html")
+	fetched := time.Date(2017, 8, 1, 12, 0, 0, 0, time.UTC)
+	w, _ := warc.NewWriter(filepath.Join(dir, "archive"), "wdj", 0)
+	w.Write(
+		&warc.Record{Type: warc.TypeResponse, TargetURI: wdj.AppPageURL("com.tencent.mm"), Date: fetched,
+			Block: append([]byte("HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n"), page...)},
+		&warc.Record{Type: warc.TypeResponse, TargetURI: wdj.ReviewURL("com.tencent.mm"), Date: fetched,
+			Block: append([]byte("HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n"), page...)},
+		&warc.Record{Type: warc.TypeResponse, TargetURI: "http://www.wandoujia.com/search?key=a",
+			Block: []byte("HTTP/1.1 200 OK\r\n\r\n")},
+	)
+	w.Close()
+
+	var out bytes.Buffer
+	sum, err := Reparse(dir, 3, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Total != 9 || sum.Parsed["wdj"] != 4 || sum.Parsed["sjqq"] != 3 || len(sum.Failures) != 2 {
+		t.Errorf("summary = %+v", sum)
+	}
+	if n := strings.Count(out.String(), "\n"); n != 7 {
+		t.Errorf("%d json lines, want 7", n)
+	}
+	if !strings.Contains(out.String(), `"CrawledTime":"2017-08-01T12:00:00Z"`) {
+		t.Error("crawled time of archived page should be its WARC-Date")
+	}
+	if !strings.Contains(sum.Failures[filepath.Join(dir, "page.html")], "unknown source") {
+		t.Errorf("failures = %v", sum.Failures)
+	}
+
+	var report bytes.Buffer
+	sum.Print(&report)
+	if !strings.HasPrefix(report.String(), "pages 9, parsed 7, failed 2\n") {
+		t.Errorf("report = %s", report.String())
+	}
+}
+
+func TestReparserOfURI(t *testing.T) {
+	for uri, want := range map[string]string{
+		"http://www.wandoujia.com/apps/com.tencent.mm":                    "wdj",
+		"http://www.wandoujia.com/apps/com.tencent.mm/comment2":           "",
+		"http://www.wandoujia.com/apps/com.tencent.mm?from=search":        "",
+		"http://www.wandoujia.com/apps/":                                  "",
+		"http://sj.qq.com/myapp/detail.htm?apkName=com.tencent.mm":        "sjqq",
+		"http://sj.qq.com/myapp/detail.htm?apkName=com.tencent.mm&info=1": "",
+		"http://www.wandoujia.com/search?key=a":                           "",
+	} {
+		got := ""
+		if rp := reparserOfURI(uri); rp != nil {
+			got = rp.Source
+		}
+		if got != want {
+			t.Errorf("reparserOfURI(%s) = %q, want %q", uri, got, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"strings"
+)
+
+import (
+	"github.com/Vonng/go-android-search/wdj"
+	"github.com/Vonng/go-android-search/sjqq"
+	"github.com/Vonng/go-android-search/review"
+	"github.com/Vonng/go-android-search/sentiment"
+	log "github.com/Sirupsen/logrus"
+)
+
+// reviewKnownIDs is how many latest review ids are loaded to detect where last crawl stopped
+const reviewKnownIDs = 500
+
+// reviewFetchers maps source to its review fetcher
+var reviewFetchers = map[string]review.Fetcher{
+	"wdj":  wdj.FetchReviews,
+	"sjqq": sjqq.FetchReviews,
+}
+
+// HandleReviews crawls new reviews of apk in source into review table, and returns
+// total review count reported by source (0 if unknown). Crawling stops at the first
+// review already in table, new reviews are saved only when crawl succeeds, so that
+// a failed page won't leave a gap behind known reviews. When review_pages is reached
+// before a known review, the rest is recorded as a gap and continued by later crawls
+// with the same page budget, see fillReviewGap. review_pages 0 crawls all pages
+func HandleReviews(source, apk string) (total int64, err error) {
+	pages := Conf.Source(source).ReviewPages
+	fetch, ok := reviewFetchers[source]
+	if pages < 0 || !ok {
+		return 0, nil
+	}
+	known, err := review.KnownIDs(Pg, source, apk, reviewKnownIDs)
+	if err != nil {
+		return 0, err
+	}
+	reviews, total, next, err := review.Crawl(fetch, apk, "", func(r review.Review) bool { return known[r.ID] }, pages)
+	if err != nil {
+		return total, fmt.Errorf("reviews of %s incomplete, %d not saved: %s", apk, len(reviews), err)
+	}
+	if err = saveReviews(source, apk, reviews); err != nil {
+		return total, err
+	}
+	if next != "" {
+		gap := &review.Gap{Source: source, AppID: apk, CreatedTime: time.Now(), Cursor: next, Before: review.Oldest(reviews)}
+		if err = gap.Save(Pg); err != nil {
+			return total, err
+		}
+		log.Infof("[REVIEW] %s %s has more than %d pages of new reviews, rest is continued later", source, apk, pages)
+	} else if err = fillReviewGap(fetch, source, apk, pages); err != nil {
+		log.Warnf("[REVIEW] %s %s fill gap failed: %s", source, apk, err.Error())
+	}
+	return total, nil
+}
+
+// fillReviewGap continues the newest gap of apk for at most pages pages. The gap is closed
+// when a stored review older than reviews crawled so far is met, or reviews run out
+func fillReviewGap(fetch review.Fetcher, source, apk string, pages int) error {
+	gap, err := review.LoadGap(Pg, source, apk)
+	if err != nil || gap == nil {
+		return err
+	}
+	var lookupErr error
+	reviews, _, next, err := review.Crawl(fetch, apk, gap.Cursor, func(r review.Review) bool {
+		if !r.Date.Before(gap.Before) || lookupErr != nil {
+			return false
+		}
+		found, err := review.Exists(Pg, source, apk, r.ID)
+		lookupErr = err
+		return found
+	}, pages)
+	if err == nil {
+		err = lookupErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = saveReviews(source, apk, reviews); err != nil {
+		return err
+	}
+	if next == "" {
+		return gap.Delete(Pg)
+	}
+	gap.Cursor = next
+	if oldest := review.Oldest(reviews); !oldest.IsZero() && oldest.Before(gap.Before) {
+		gap.Before = oldest
+	}
+	return gap.Save(Pg)
+}
+
+// saveReviews writes crawled reviews of apk into review table
+func saveReviews(source, apk string, reviews []review.Review) error {
+	n, err := review.Save(Pg, reviews)
+	if err != nil {
+		return err
+	}
+	RowsUpserted.WithLabelValues("review").Add(float64(n))
+	log.Debugf("[REVIEW] %s %s found %d, add %d", source, apk, len(reviews), n)
+	return nil
+}
+
+// Sentiment prints weekly sentiment rollup and top complaints per version of apk
+// from stored reviews of all sources, along with current version and release note
+func Sentiment(apk string) error {
+	var reviews []review.Review
+	if err := Pg.Model(&reviews).Where("app_id = ?", apk).OrderExpr("date").Select(); err != nil {
+		return err
+	}
+	if len(reviews) == 0 {
+		fmt.Printf("no review of %s\n", apk)
+		return nil
+	}
+	apps, err := GetApp(apk)
+	if err != nil {
+		return err
+	}
+	for _, app := range apps {
+		fmt.Printf("%s %s %s\n", app.Source, app.Version, strings.Join(strings.Fields(app.ReleaseNote), " "))
+	}
+
+	fmt.Printf("\n%-10s %6s %6s %6s %7s %6s  %-16s %s\n", "week", "count", "pos", "neg", "score", "rating", "versions", "complaints")
+	for _, w := range sentiment.Weekly(reviews) {
+		var complaints []string
+		for _, p := range w.Complaints {
+			complaints = append(complaints, fmt.Sprintf("%s(%d)", p.Text, p.Count))
+		}
+		fmt.Printf("%-10s %6d %6d %6d %7.3f %6.2f  %-16s %s\n", w.Start.Format("2006-01-02"),
+			w.Count, w.Positive, w.Negative, w.Score, w.Rating, strings.Join(w.Versions, ","), strings.Join(complaints, " "))
+	}
+
+	fmt.Println()
+	top := sentiment.TopComplaints(reviews, 10)
+	versions := make([]string, 0, len(top))
+	for version := range top {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	for _, version := range versions {
+		phrases := top[version]
+		if version == "" {
+			version = "unknown"
+		}
+		var list []string
+		for _, p := range phrases {
+			list = append(list, fmt.Sprintf("%s(%d)", p.Text, p.Count))
+		}
+		fmt.Printf("%-16s %s\n", version, strings.Join(list, " "))
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"errors"
+	"context"
+	"strings"
+	"net/http"
+)
+
+import (
+	"github.com/Vonng/go-android-search/wdj"
+	"github.com/Vonng/go-android-search/fill"
+	"github.com/Vonng/go-android-search/sjqq"
+	"github.com/Vonng/go-android-search/warc"
+	"github.com/Vonng/go-android-search/extract"
+	log "github.com/Sirupsen/logrus"
+)
+
+// Limiter allows at most rate requests per second, nil Limiter means unlimited
+type Limiter struct {
+	tick <-chan time.Time
+}
+
+// NewLimiter returns nil if rate is not positive
+func NewLimiter(rate float64) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &Limiter{time.NewTicker(time.Duration(float64(time.Second) / rate)).C}
+}
+
+// Wait blocks until next request is allowed
+func (l *Limiter) Wait() {
+	if l != nil {
+		<-l.tick
+	}
+}
+
+// limitedTransport waits limiter before each round trip
+type limitedTransport struct {
+	limiter *Limiter
+	base    http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.limiter.Wait()
+	return t.base.RoundTrip(req)
+}
+
+// NewClient builds http client with timeout and rate limit of source,
+// responses are archived by archive if it's not nil. Fetch duration is measured
+// around the network round trip only, excluding throttled time and archiving
+func NewClient(c *SourceConfig, archive *warc.Writer) *http.Client {
+	var base http.RoundTripper = &metricsTransport{http.DefaultTransport}
+	if archive != nil {
+		base = &warc.Transport{Base: base, Writer: archive, OnError: func(req *http.Request, err error) {
+			log.Warnf("[ARCHIVE] archive %s failed: %s", req.URL, err.Error())
+		}}
+	}
+	return &http.Client{
+		Timeout:   c.Timeout,
+		Transport: &limitedTransport{NewLimiter(c.RateLimit), base},
+	}
+}
+
+// Archives holds warc writers of sources, closed on shutdown
+var Archives []*warc.Writer
+
+// NewArchive returns warc writer of source if archiving is enabled, otherwise nil
+func NewArchive(c *Config, source string) *warc.Writer {
+	if c.Archive.Dir == "" {
+		return nil
+	}
+	w, err := warc.NewWriter(c.Archive.Dir, source, c.Archive.MaxSize<<20)
+	if err != nil {
+		log.Errorf("[ARCHIVE] archiving of %s disabled: %s", source, err.Error())
+		return nil
+	}
+	Archives = append(Archives, w)
+	return w
+}
+
+// CloseArchives closes all warc writers
+func CloseArchives() {
+	for _, w := range Archives {
+		if err := w.Close(); err != nil {
+			log.Errorf("[ARCHIVE] close %s archive failed: %s", w.Prefix, err.Error())
+		}
+	}
+	Archives = nil
+}
+
+// Task is a package message fanned out to all sources
+type Task struct {
+	Msg Message
+	sync.Mutex
+	remain  int
+	results map[string]error
+	done    func(*Task)
+}
+
+// NewTask builds task for message which will be handled by n sources,
+// done is called when all sources finished
+func NewTask(msg Message, n int, done func(*Task)) *Task {
+	return &Task{Msg: msg, remain: n, results: make(map[string]error, n), done: done}
+}
+
+// Task_Finish records result of a source
+func (t *Task) Finish(source string, err error) {
+	t.Lock()
+	t.results[source] = err
+	t.remain--
+	last := t.remain == 0
+	t.Unlock()
+	if last && t.done != nil {
+		t.done(t)
+	}
+}
+
+// Task_Report returns per-source completion, e.g. `wdj:ok sjqq:failed`
+func (t *Task) Report() string {
+	t.Lock()
+	defer t.Unlock()
+	var parts []string
+	for _, name := range SourceNames {
+		if err, ok := t.results[name]; ok {
+			if err != nil {
+				parts = append(parts, name+":failed")
+			} else {
+				parts = append(parts, name+":ok")
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Task_Failed returns number of failed sources
+func (t *Task) Failed() (n int) {
+	t.Lock()
+	defer t.Unlock()
+	for _, err := range t.results {
+		if err != nil {
+			n++
+		}
+	}
+	return
+}
+
+// ErrSourceBusy is the result of a package task dropped by a source whose queue is full
+var ErrSourceBusy = errors.New("source queue is full")
+
+// Source is a crawling source with its own worker pool and queue,
+// so a slow source won't block others
+type Source struct {
+	Name    string
+	Handle  func(apk string) error
+	Workers int
+	queue   chan *Task
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	resume  chan struct{} // not nil when paused, closed on resume
+}
+
+// Source_Start launches worker pool of source
+func (s *Source) Start() {
+	log.Infof("[%s] init with %d worker, queue size %d", s.Tag(), s.Workers, cap(s.queue))
+	for i := 1; i <= s.Workers; i++ {
+		s.wg.Add(1)
+		go s.work(i)
+	}
+}
+
+// Source_Submit puts task into source queue without blocking, so a paused or throttled source
+// never holds up dispatchers and other sources. When queue is full the task is dropped by
+// this source and finished with ErrSourceBusy, counted as failure of class `busy`
+func (s *Source) Submit(t *Task) {
+	SourceClaimed.WithLabelValues(s.Name).Inc()
+	select {
+	case s.queue <- t:
+	default:
+		log.Warnf("[%s] queue full, drop Package=%s", s.Tag(), t.Msg.ID)
+		ObserveResult(s.Name, ErrSourceBusy)
+		t.Finish(s.Name, ErrSourceBusy)
+	}
+}
+
+// Source_Stop closes queue and waits until all queued tasks are done
+// A paused source is resumed to drain its queue
+func (s *Source) Stop() {
+	s.Resume()
+	close(s.queue)
+	s.wg.Wait()
+	log.Infof("[%s] finish", s.Tag())
+}
+
+// Source_Pause stops workers from taking new tasks, tasks are kept in queue
+func (s *Source) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resume == nil {
+		s.resume = make(chan struct{})
+	}
+}
+
+// Source_Resume lets paused workers continue
+func (s *Source) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resume != nil {
+		close(s.resume)
+		s.resume = nil
+	}
+}
+
+// Source_Paused tells whether source is paused
+func (s *Source) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.resume != nil
+}
+
+// Source_Queued returns number of tasks waiting in source queue
+func (s *Source) Queued() int {
+	return len(s.queue)
+}
+
+// wait blocks while source is paused
+func (s *Source) wait() {
+	s.mu.Lock()
+	resume := s.resume
+	s.mu.Unlock()
+	if resume != nil {
+		<-resume
+	}
+}
+
+// Source_Tag is used as log prefix
+func (s *Source) Tag() string {
+	return strings.ToUpper(s.Name)
+}
+
+// work handles tasks from source queue
+func (s *Source) work(id int) {
+	defer s.wg.Done()
+	name := fmt.Sprintf("%s:%d", s.Name, id)
+	States.Set(name, "")
+	for t := range s.queue {
+		s.wait()
+		States.Set(name, t.Msg.String())
+		err := s.Handle(t.Msg.ID)
+		if err != nil {
+			log.Errorf("[%s:%d] handle Package=%s failed: %s", s.Tag(), id, t.Msg.ID, err.Error())
+		} else {
+			log.Infof("[%s:%d] done Package=%s", s.Tag(), id, t.Msg.ID)
+		}
+		ObserveResult(s.Name, err)
+		States.Set(name, "")
+		t.Finish(s.Name, err)
+	}
+}
+
+// Sources holds all enabled sources in handling order
+var Sources []*Source
+
+// SetupSources builds enabled sources and http clients of stores according to config
+func SetupSources(c *Config) {
+	handlers := map[string]func(string) error{
+		"wdj":  HandleWdj,
+		"sjqq": HandleSjqq,
+	}
+	if FillRates.Window != c.Drift.Window || FillRates.Tolerance != c.Drift.Tolerance {
+		FillRates = fill.NewTracker(c.Drift.Window, c.Drift.Tolerance) // keep baselines across reloads otherwise
+	}
+	SetupAlerts(c)
+	CloseArchives()
+	wdj.Client = NewClient(c.Source("wdj"), NewArchive(c, "wdj"))
+	sjqq.Client = NewClient(c.Source("sjqq"), NewArchive(c, "sjqq"))
+	if err := LoadSelectors(c.Selectors); err != nil {
+		log.Errorf("[SPEC] load selectors %s failed: %s", c.Selectors, err.Error())
+	}
+
+	Sources = nil
+	for _, name := range c.EnabledSources() {
+		Sources = append(Sources, &Source{
+			Name:    name,
+			Handle:  handlers[name],
+			Workers: c.Source(name).Workers,
+			queue:   make(chan *Task, c.Source(name).Queue),
+		})
+	}
+}
+
+// specSetters replaces extraction rules of each source, see wdj.SetSpec
+var specSetters = map[string]func(*extract.Spec) error{
+	"wdj":  wdj.SetSpec,
+	"sjqq": sjqq.SetSpec,
+}
+
+// LoadSelectors overrides builtin selectors of sources with rules in given file keyed by source name,
+// sources absent from file or an empty filename use builtin rules. A source keeps its current rules
+// if its override is invalid, other sources are still applied.
+func LoadSelectors(filename string) (err error) {
+	var specs map[string]*extract.Spec
+	if filename != "" {
+		if specs, err = extract.Load(filename); err != nil {
+			return err
+		}
+	}
+	var errs []string
+	for name := range specs {
+		if !isSource(name) {
+			errs = append(errs, fmt.Sprintf("unknown source %s", name))
+		}
+	}
+	for _, name := range SourceNames {
+		if err := specSetters[name](specs[name]); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// WatchSelectors reloads selectors file once it changes until ctx is done
+func WatchSelectors(ctx context.Context, filename string, interval time.Duration) {
+	if filename == "" {
+		return
+	}
+	extract.Watch(ctx, filename, interval, func() {
+		if err := LoadSelectors(filename); err != nil {
+			log.Errorf("[SPEC] reload selectors %s failed: %s", filename, err.Error())
+			return
+		}
+		log.Infof("[SPEC] selectors reloaded from %s", filename)
+	})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"time"
+	"errors"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+import (
+	"github.com/Vonng/go-android-search/wdj"
+	"github.com/Vonng/go-android-search/sjqq"
+)
+
+func TestSourcePools(t *testing.T) {
+	release := make(chan struct{})
+	fastDone := make(chan string, 3)
+	fast := &Source{Name: "wdj", Workers: 1, queue: make(chan *Task, 3), Handle: func(apk string) error {
+		fastDone <- apk
+		return nil
+	}}
+	slow := &Source{Name: "sjqq", Workers: 1, queue: make(chan *Task, 3), Handle: func(apk string) error {
+		<-release
+		return errors.New("timeout")
+	}}
+	fast.Start()
+	slow.Start()
+
+	reports := make(chan string, 3)
+	for _, apk := range []string{"a", "b", "c"} {
+		task := NewTask(Message{TypePackage, apk}, 2, func(t *Task) {
+			reports <- t.Msg.ID + " " + t.Report()
+		})
+		fast.Submit(task)
+		slow.Submit(task)
+	}
+
+	// fast source finishes all tasks while slow one is blocked
+	for i := 0; i < 3; i++ {
+		select {
+		case <-fastDone:
+		case <-time.After(time.Second):
+			t.Fatal("fast source is blocked by slow source")
+		}
+	}
+	select {
+	case r := <-reports:
+		t.Fatalf("task reported before all sources finished: %s", r)
+	default:
+	}
+
+	close(release)
+	fast.Stop()
+	slow.Stop()
+	for i := 0; i < 3; i++ {
+		if r := <-reports; r[2:] != "wdj:ok sjqq:failed" {
+			t.Errorf("report = %q", r)
+		}
+	}
+}
+
+func TestSourceSubmitFull(t *testing.T) {
+	paused := &Source{Name: "sjqq", Workers: 1, queue: make(chan *Task, 1), Handle: func(apk string) error { return nil }}
+	paused.Pause()
+	paused.Start()
+	reports := make(chan string, 3)
+	submit := func(apk string) {
+		paused.Submit(NewTask(Message{TypePackage, apk}, 1, func(t *Task) { reports <- t.Msg.ID + " " + t.Report() }))
+	}
+
+	// the worker takes a and waits for resume, b fills the queue, c is dropped without blocking
+	submit("a")
+	for deadline := time.Now().Add(time.Second); paused.Queued() > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan struct{})
+	go func() {
+		submit("b")
+		submit("c")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("submit blocks on full queue")
+	}
+	if r := <-reports; r != "c sjqq:failed" {
+		t.Errorf("dropped task report = %q", r)
+	}
+
+	paused.Stop()
+	for _, want := range []string{"a sjqq:ok", "b sjqq:ok"} {
+		if r := <-reports; r != want {
+			t.Errorf("report = %q, want %q", r, want)
+		}
+	}
+}
+
+func TestLoadSelectors(t *testing.T) {
+	defer LoadSelectors("")
+	dir, err := ioutil.TempDir("", "selectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "selectors.yml")
+	write := func(data string) {
+		if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("wdj:\n  fields:\n    version: {selector: dd.version}\n")
+	if err = LoadSelectors(filename); err != nil {
+		t.Fatal(err)
+	}
+	if got := wdj.Spec().Fields["version"][0].Selector; got != "dd.version" {
+		t.Errorf("wdj version selector = %s, want dd.version", got)
+	}
+	if sjqq.Spec() != sjqq.DefaultSpec() {
+		t.Error("sjqq should use builtin selectors")
+	}
+
+	write("wdj:\n  fields:\n    version: {selector: dd.v2, post: [unknown]}\nsjqq:\n  fields:\n    name: {selector: div.name}\nxyz: {}\n")
+	if err = LoadSelectors(filename); err == nil {
+		t.Error("invalid override and unknown source should be reported")
+	}
+	if got := wdj.Spec().Fields["version"][0].Selector; got != "dd.version" {
+		t.Errorf("invalid override should keep previous rules, got %s", got)
+	}
+	if got := sjqq.Spec().Fields["name"][0].Selector; got != "div.name" {
+		t.Errorf("valid override of other source should apply, got %s", got)
+	}
+
+	if err = LoadSelectors(""); err != nil || wdj.Spec() != wdj.DefaultSpec() {
+		t.Errorf("empty filename should restore builtin selectors: %v", err)
+	}
+	if err = LoadSelectors(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("missing file should fail")
+	}
+}
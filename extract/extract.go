@@ -0,0 +1,244 @@
+package extract
+
+import (
+	"fmt"
+	"time"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vonng/go-android-search/fill"
+)
+
+// ErrRequired 表示必需的字段没有抽取到
+var ErrRequired = errors.New("required field is empty")
+
+// fieldKind 为支持的目标字段类型
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindInt
+	kindFloat
+	kindTime
+	kindList
+)
+
+type structField struct {
+	index int
+	kind  fieldKind
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structFields 返回结构体中可抽取的字段：string, int64, float64, time.Time 与 []string，键为下划线风格的字段名，与 fill.Fields 一致
+func structFields(prototype interface{}) (map[string]structField, error) {
+	rt := reflect.TypeOf(prototype)
+	if rt == nil || rt.Kind() != reflect.Ptr || rt.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("prototype should be pointer to struct, got %v", rt)
+	}
+	rt = rt.Elem()
+	fields := make(map[string]structField, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		var kind fieldKind
+		switch {
+		case f.Type.Kind() == reflect.String:
+			kind = kindString
+		case f.Type.Kind() == reflect.Int64:
+			kind = kindInt
+		case f.Type.Kind() == reflect.Float64:
+			kind = kindFloat
+		case f.Type == timeType:
+			kind = kindTime
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.String:
+			kind = kindList
+		default:
+			continue
+		}
+		fields[fill.Underscore(f.Name)] = structField{i, kind}
+	}
+	return fields, nil
+}
+
+// Extract 按规则从文档中抽取字段写入 v，v 须与 Compile 时的 prototype 类型相同
+// 每个字段依次尝试各条规则，取第一个非空结果，全部为空时字段保持原值
+// 必需字段为空时返回 ErrRequired，其余字段照常抽取
+func (s *Spec) Extract(doc *goquery.Document, v interface{}) (err error) {
+	if s.fields == nil {
+		return errors.New("extract: spec is not compiled")
+	}
+	scopes := make(map[string]*goquery.Selection, len(s.Scopes))
+	for name, sel := range s.Scopes {
+		scopes[name] = doc.Find(sel)
+	}
+	rv := reflect.ValueOf(v).Elem()
+	for name, rules := range s.Fields {
+		field := rv.Field(s.fields[name])
+		ok, required := false, false
+		for _, rule := range rules {
+			required = required || rule.Required
+			if ok {
+				continue
+			}
+			root := doc.Selection
+			if rule.Scope != "" {
+				root = scopes[rule.Scope]
+			}
+			ok = rule.apply(root.Find(rule.Selector), field)
+		}
+		if !ok && required {
+			err = fmt.Errorf("%s: %s", name, ErrRequired)
+		}
+	}
+	return err
+}
+
+// Check 检查 v 中的必需字段是否非零，用于在其他来源回退填充后重新检查
+func (s *Spec) Check(v interface{}) error {
+	if s.fields == nil {
+		return errors.New("extract: spec is not compiled")
+	}
+	rv := reflect.ValueOf(v).Elem()
+	for name, rules := range s.Fields {
+		for _, rule := range rules {
+			if rule.Required && isZero(rv.Field(s.fields[name])) {
+				return fmt.Errorf("%s: %s", name, ErrRequired)
+			}
+		}
+	}
+	return nil
+}
+
+// isZero 判断支持的字段类型是否为零值
+func isZero(field reflect.Value) bool {
+	if field.Type() == timeType {
+		return field.Interface().(time.Time).IsZero()
+	}
+	switch field.Kind() {
+	case reflect.String, reflect.Slice:
+		return field.Len() == 0
+	case reflect.Int64:
+		return field.Int() == 0
+	case reflect.Float64:
+		return field.Float() == 0
+	}
+	return false
+}
+
+// apply 从选择的元素中取值写入字段，返回是否取到非空值
+func (r *Rule) apply(sel *goquery.Selection, field reflect.Value) bool {
+	if r.List {
+		var list []string
+		sel.Each(func(i int, s *goquery.Selection) {
+			if v := r.process(r.raw(s)); v != "" {
+				list = append(list, v)
+			}
+		})
+		if len(list) == 0 {
+			return false
+		}
+		field.Set(reflect.ValueOf(list))
+		return true
+	}
+
+	v := r.process(r.raw(sel))
+	if v == "" {
+		return false
+	}
+	switch field.Type() {
+	case timeType:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return false
+		}
+		field.Set(reflect.ValueOf(t))
+		return true
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(v)
+	case reflect.Int64:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return false
+			}
+			i = int64(f)
+		}
+		field.SetInt(i)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false
+		}
+		field.SetFloat(f)
+	}
+	return true
+}
+
+// raw 取元素的原始值
+func (r *Rule) raw(sel *goquery.Selection) string {
+	switch {
+	case r.Attr != "":
+		s, _ := sel.Attr(r.Attr)
+		return strings.TrimSpace(s)
+	case r.HTML:
+		s, err := sel.Html()
+		if err != nil {
+			return ""
+		}
+		// 没有后处理时沿用旧规则的行为，<br> 转为换行；有后处理时交给 text、sanitize 等处理原始HTML
+		if len(r.Post) == 0 {
+			s = strings.Replace(s, "<br>", "\n", -1)
+			s = strings.Replace(s, "<br/>", "\n", -1)
+		}
+		return strings.TrimSpace(s)
+	case r.Node != "":
+		if len(sel.Nodes) == 0 || sel.Nodes[0] == nil {
+			return ""
+		}
+		node := sel.Nodes[0].FirstChild
+		if r.Node == "last" {
+			node = sel.Nodes[0].LastChild
+		}
+		if node == nil || node.Type != 1 { // html.TextNode
+			return ""
+		}
+		return strings.TrimSpace(node.Data)
+	}
+	return strings.TrimSpace(sel.Text())
+}
+
+// process 依次应用正则与后处理，任一步骤失败时返回空串
+func (r *Rule) process(v string) string {
+	if v == "" {
+		return ""
+	}
+	if r.re != nil {
+		m := r.re.FindStringSubmatch(v)
+		switch {
+		case m == nil:
+			return ""
+		case len(m) > 1:
+			v = m[1]
+		default:
+			v = m[0]
+		}
+	}
+	for _, p := range r.post {
+		var err error
+		if v, err = p.fn(v, p.arg); err != nil || v == "" {
+			return ""
+		}
+	}
+	return v
+}
@@ -0,0 +1,253 @@
+package extract
+
+import (
+	"os"
+	"time"
+	"strings"
+	"testing"
+	"io/ioutil"
+	"path/filepath"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vonng/go-android-search/norm"
+)
+
+const testPage = `<html><body data-pn="com.example">
+<p class="title"> Example </p>
+<dl class="info">
+	<dd class="size">2.5万</dd>
+	<dd class="sys">Android 4.0 以上<a>查看</a></dd>
+	<a class="tag">工具</a><a class="tag"> </a><a class="tag">效率</a>
+</dl>
+<div class="rate">4.3分</div>
+<div class="desc">line1<br>line2</div>
+<time datetime="2017年07月29日"></time>
+<script>var x = {appId: "42", iconUrl: "http://icon",};</script>
+</body></html>`
+
+type testApp struct {
+	ID          string
+	Name        string
+	Icon        string
+	System      string
+	Description string
+	Size        int64
+	Rating      int64
+	Stars       float64
+	AppID       int64
+	Tags        []string
+	ReleaseTime time.Time
+	Missing     string
+}
+
+const testSpec = `
+scopes:
+  info: dl.info
+fields:
+  id:           {selector: body, attr: data-pn, required: true}
+  name:         {selector: p.title}
+  icon:
+    - {selector: img.icon, attr: src}
+    - {selector: script, regex: 'iconUrl:\s*"(\S+)",'}
+  system:       {scope: info, selector: dd.sys, node: first, post: ["trimPrefix:Android ", "trimSuffix:以上"]}
+  description:  {selector: div.desc, html: true, post: [text]}
+  size:         {scope: info, selector: dd.size, post: [zh]}
+  rating:       {selector: div.rate, post: ["trimRight:分", "scale:20"]}
+  stars:        {selector: div.rate, post: ["trimRight:分"]}
+  app_id:       {selector: script, regex: 'appId:\s*"(\d+)",'}
+  tags:         {scope: info, selector: a.tag, list: true}
+  release_time: {selector: time, attr: datetime, post: ["time:2006年01月02日"]}
+  missing:      {selector: span.none}
+`
+
+var testProcs = map[string]Processor{
+	"zh": IntProcessor(func(s string) (int64, bool) {
+		if strings.HasSuffix(s, "万") {
+			return 25000, true
+		}
+		return 0, false
+	}),
+}
+
+func compile(t *testing.T, data string) *Spec {
+	spec, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = spec.Compile(new(testApp), testProcs); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func document(t *testing.T, html string) *goquery.Document {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestExtract(t *testing.T) {
+	app := &testApp{Missing: "keep"}
+	if err := compile(t, testSpec).Extract(document(t, testPage), app); err != nil {
+		t.Fatal(err)
+	}
+	want := testApp{
+		ID: "com.example", Name: "Example", Icon: "http://icon", System: "4.0",
+		Description: "line1\nline2", Size: 25000, Rating: 86, Stars: 4.3, AppID: 42,
+		Tags: []string{"工具", "效率"}, ReleaseTime: time.Date(2017, 7, 29, 0, 0, 0, 0, norm.Location),
+		Missing: "keep",
+	}
+	if app.ID != want.ID || app.Name != want.Name || app.Icon != want.Icon || app.System != want.System ||
+		app.Description != want.Description || app.Size != want.Size || app.Rating != want.Rating || app.Stars != want.Stars ||
+		app.AppID != want.AppID || strings.Join(app.Tags, ",") != strings.Join(want.Tags, ",") ||
+		!app.ReleaseTime.Equal(want.ReleaseTime) || app.Missing != want.Missing {
+		t.Errorf("got %+v\nwant %+v", *app, want)
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	for rule, want := range map[string]string{
+		`{selector: div.desc, html: true}`:                   "line1\nline2",
+		`{selector: div.desc, html: true, post: [sanitize]}`: "line1<br>line2",
+	} {
+		app := new(testApp)
+		if err := compile(t, "fields: {id: {selector: body, attr: data-pn}, description: "+rule+"}").Extract(document(t, testPage), app); err != nil {
+			t.Fatal(err)
+		}
+		if app.Description != want {
+			t.Errorf("%s: description = %q, want %q", rule, app.Description, want)
+		}
+	}
+}
+
+func TestExtractRequired(t *testing.T) {
+	app := new(testApp)
+	err := compile(t, testSpec).Extract(document(t, `<html><body><p class="title">x</p></body></html>`), app)
+	if err == nil || !strings.Contains(err.Error(), ErrRequired.Error()) {
+		t.Errorf("missing required field: err = %v", err)
+	}
+	if app.Name != "x" {
+		t.Errorf("other fields should still be extracted, name = %q", app.Name)
+	}
+	if err = compile(t, testSpec).Check(app); err == nil {
+		t.Error("check should report missing required field")
+	}
+	app.ID = "filled.elsewhere"
+	if err = compile(t, testSpec).Check(app); err != nil {
+		t.Errorf("check after filling: %s", err)
+	}
+	if err := new(Spec).Extract(document(t, testPage), app); err == nil {
+		t.Error("uncompiled spec should fail")
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	cases := []struct{ name, arg, in, want string }{
+		{"number", "", "1.28亿", "128000000"},
+		{"number", "", "5万+", "50000"},
+		{"size", "", "25 MB", "26214400"},
+		{"percent", "", "97.00%", "97"},
+		{"stars", "", "4.3分", "86"},
+		{"stars", "10", "8.8", "88"},
+		{"date", "", "2017年07月29日", "2017-07-29T00:00:00+08:00"},
+		{"time", "2006年01月02日", "2017年07月29日", "2017-07-29T00:00:00+08:00"},
+	}
+	for _, c := range cases {
+		if got, err := Builtins[c.name](c.in, c.arg); got != c.want || err != nil {
+			t.Errorf("%s:%s(%q) = %q, %v; want %q", c.name, c.arg, c.in, got, err, c.want)
+		}
+	}
+	for _, name := range []string{"number", "size", "percent", "stars", "date"} {
+		if _, err := Builtins[name]("暂无", ""); err != ErrSkip {
+			t.Errorf("%s(暂无) = %v, want ErrSkip", name, err)
+		}
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	for _, data := range []string{
+		`fields: {nothing: {selector: p}}`,
+		`fields: {name: {selector: ""}}`,
+		`fields: {name: {scope: nowhere, selector: p}}`,
+		`fields: {name: {selector: p, node: middle}}`,
+		`fields: {name: {selector: p, list: true}}`,
+		`fields: {tags: {selector: p}}`,
+		`fields: {name: {selector: p, regex: "("}}`,
+		`fields: {name: {selector: p, post: [unknown]}}`,
+	} {
+		spec, err := Parse([]byte(data))
+		if err != nil {
+			t.Errorf("%s: %s", data, err)
+			continue
+		}
+		if err = spec.Compile(new(testApp), testProcs); err == nil {
+			t.Errorf("%s: should fail to compile", data)
+		}
+	}
+	if err := new(Spec).Compile(testApp{}, nil); err == nil {
+		t.Error("non-pointer prototype should fail")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := compile(t, testSpec)
+	override, err := Parse([]byte(`
+scopes:
+  info: div.info
+fields:
+  name: {selector: div.name}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := base.Merge(override)
+	if err = merged.Compile(new(testApp), testProcs); err != nil {
+		t.Fatal(err)
+	}
+	if merged.Scopes["info"] != "div.info" || merged.Fields["name"][0].Selector != "div.name" {
+		t.Errorf("override not applied: %+v", merged)
+	}
+	if base.Scopes["info"] != "dl.info" || base.Fields["name"][0].Selector != "p.title" {
+		t.Error("merge should not modify the base spec")
+	}
+	if merged.Fields["icon"][1] == base.Fields["icon"][1] {
+		t.Error("merged rules should not be shared with the base spec")
+	}
+
+	app := new(testApp)
+	page := strings.Replace(testPage, `<p class="title"> Example </p>`, `<div class="name">New</div>`, 1)
+	if err = merged.Extract(document(t, page), app); err != nil {
+		t.Fatal(err)
+	}
+	if app.Name != "New" {
+		t.Errorf("name = %q, want New", app.Name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "selectors.yml")
+	data := "wdj:\n  fields:\n    name:\n      - {selector: p.a}\n      - {selector: p.b}\nsjqq:\n  fields:\n    name: {selector: div.c}\n"
+	if err = ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	specs, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs["wdj"].Fields["name"]) != 2 || specs["sjqq"].Fields["name"][0].Selector != "div.c" {
+		t.Errorf("Load = %+v", specs)
+	}
+	if _, err = Load(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("missing file should fail")
+	}
+}
@@ -0,0 +1,109 @@
+package extract
+
+import (
+	"time"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/Vonng/go-android-search/norm"
+	"github.com/Vonng/go-android-search/richtext"
+)
+
+// Processor 是一个后处理函数，arg 为规则中冒号后的参数，返回错误时字段视为未抽取到
+// 时间字段须输出 RFC3339 格式，整数与小数字段须输出整数或小数，整数字段截断小数部分
+type Processor func(v, arg string) (string, error)
+
+// ErrSkip 表示值应被忽略，如 `暂无`
+var ErrSkip = errors.New("skipped")
+
+// Builtins 为内置的后处理函数
+var Builtins = map[string]Processor{
+	// trimLeft:V 与 strings.TrimLeft 相同，去除左侧属于字符集的字符
+	"trimLeft": func(v, arg string) (string, error) { return strings.TrimSpace(strings.TrimLeft(v, arg)), nil },
+	// trimRight:下载 与 strings.TrimRight 相同
+	"trimRight": func(v, arg string) (string, error) { return strings.TrimSpace(strings.TrimRight(v, arg)), nil },
+	// trimPrefix:来自： 去除前缀
+	"trimPrefix": func(v, arg string) (string, error) { return strings.TrimSpace(strings.TrimPrefix(v, arg)), nil },
+	// trimSuffix:以上 去除后缀
+	"trimSuffix": func(v, arg string) (string, error) { return strings.TrimSpace(strings.TrimSuffix(v, arg)), nil },
+	// skip:暂无 值等于参数时忽略
+	"skip": func(v, arg string) (string, error) {
+		if v == arg {
+			return "", ErrSkip
+		}
+		return v, nil
+	},
+	// scale:20 将小数乘以参数，如评分 `4.3` => `86`，结果截断为整数
+	"scale": func(v, arg string) (string, error) {
+		f, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return "", err
+		}
+		k, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(int64(f*k), 10), nil
+	},
+	// time:2006年01月02日 按格式解析时间，未注明时区时为北京时间
+	"time": func(v, arg string) (string, error) {
+		t, err := time.ParseInLocation(arg, v, norm.Location)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(time.RFC3339Nano), nil
+	},
+	// date 解析常见的中文日期，如 `2017年07月05日`、`2017-07-05 15:04`、`昨天`，见 norm.Date
+	"date": func(v, arg string) (string, error) {
+		t, ok := norm.Date(v)
+		if !ok {
+			return "", ErrSkip
+		}
+		return t.Format(time.RFC3339Nano), nil
+	},
+	// number 解析带中文量词的数字，如 `1.28亿`、`5万+`、`1,234次`
+	"number": IntProcessor(norm.Number),
+	// size 解析文件大小为字节数，如 `25 MB`
+	"size": IntProcessor(norm.Size),
+	// percent 解析百分比，如 `97.00%` => `97`
+	"percent": IntProcessor(norm.Percent),
+	// stars:5 将星级评分转为百分制，参数为满分，默认为 5，如 `4.3分` => `86`
+	"stars": func(v, arg string) (string, error) {
+		best := 0.0
+		if arg != "" {
+			var err error
+			if best, err = strconv.ParseFloat(arg, 64); err != nil {
+				return "", err
+			}
+		}
+		return IntProcessor(func(s string) (int64, bool) { return norm.Stars(s, best) })(v, arg)
+	},
+	// text 将HTML转为纯文本，保留分段与列表，见 richtext.Text
+	"text": func(v, arg string) (string, error) { return richtext.Text(v), nil },
+	// markdown 将HTML转为 Markdown
+	"markdown": func(v, arg string) (string, error) { return richtext.Markdown(v), nil },
+	// sanitize 清理HTML，只保留安全的标签与属性
+	"sanitize": func(v, arg string) (string, error) { return richtext.Sanitize(v), nil },
+	// unix 将秒级时间戳转为时间
+	"unix": func(v, arg string) (string, error) {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		return time.Unix(i, 0).Format(time.RFC3339Nano), nil
+	},
+}
+
+// IntProcessor 将返回整数的解析函数包装为后处理函数，如 norm.Number、norm.Size
+func IntProcessor(parse func(string) (int64, bool)) Processor {
+	return func(v, arg string) (string, error) {
+		i, ok := parse(v)
+		if !ok {
+			return "", ErrSkip
+		}
+		return strconv.FormatInt(i, 10), nil
+	}
+}
@@ -0,0 +1,187 @@
+package extract
+
+import (
+	"fmt"
+	"sort"
+	"regexp"
+	"strings"
+	"io/ioutil"
+)
+
+import (
+	"gopkg.in/yaml.v2"
+)
+
+// Spec 是一个来源的详情页抽取规则，字段名为下划线风格的结构体字段名，如 `install_cnt`
+//
+//	scopes:                      # 具名的范围选择器，供规则引用
+//	  info: dl.infos-list
+//	fields:
+//	  id: {selector: body, attr: data-pn, required: true}
+//	  size: {scope: info, selector: "meta[itemprop=fileSize]", attr: content, post: [size]}
+//	  icon:                      # 多条规则依次尝试，取第一个非空结果
+//	    - {selector: "script:last-of-type", regex: 'iconUrl\s*:\s*"(\S+)",'}
+//	    - {selector: "div.app-icon img", attr: src}
+type Spec struct {
+	Scopes map[string]string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+	Fields map[string]Rules  `yaml:"fields" json:"fields"`
+
+	fields map[string]int // 字段名到结构体字段下标，Compile 时建立
+}
+
+// Rule 是抽取一个字段的一条规则：在范围内选择元素，取文本、属性、HTML或文本节点，
+// 再依次经过正则与后处理
+type Rule struct {
+	Scope    string   `yaml:"scope,omitempty" json:"scope,omitempty"`       // 引用 Spec.Scopes 中的范围，为空时在整个文档中选择
+	Selector string   `yaml:"selector" json:"selector"`                     // CSS选择器
+	Attr     string   `yaml:"attr,omitempty" json:"attr,omitempty"`         // 取属性值，为空时取文本
+	HTML     bool     `yaml:"html,omitempty" json:"html,omitempty"`         // 取内部HTML，无后处理时<br>转为换行，通常配合后处理 text、markdown 或 sanitize 使用
+	Node     string   `yaml:"node,omitempty" json:"node,omitempty"`         // first/last: 取首个元素的第一个或最后一个文本子节点
+	List     bool     `yaml:"list,omitempty" json:"list,omitempty"`         // 取所有匹配元素，结果为列表，空值被丢弃
+	Regex    string   `yaml:"regex,omitempty" json:"regex,omitempty"`       // 取第一个分组，无分组时取整个匹配
+	Post     []string `yaml:"post,omitempty" json:"post,omitempty"`         // 后处理，形如 `number`、`trimRight:下载`
+	Required bool     `yaml:"required,omitempty" json:"required,omitempty"` // 字段为空时解析失败
+
+	re   *regexp.Regexp
+	post []step
+}
+
+// step 是一个编译后的后处理步骤
+type step struct {
+	name string
+	arg  string
+	fn   Processor
+}
+
+// Rules 是一个字段的若干规则，YAML中可写为单条规则或规则列表
+type Rules []*Rule
+
+func (r *Rules) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []*Rule
+	if err := unmarshal(&list); err == nil {
+		*r = list
+		return nil
+	}
+	var rule Rule
+	if err := unmarshal(&rule); err != nil {
+		return err
+	}
+	*r = Rules{&rule}
+	return nil
+}
+
+// Parse 解析YAML或JSON格式的规则，需要 Compile 后才能使用
+func Parse(data []byte) (*Spec, error) {
+	spec := new(Spec)
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Merge 返回新的规则，override 中的范围与字段覆盖当前规则中的同名项，整个字段的规则列表被替换
+// 返回的规则不与原规则共享，需要重新 Compile
+func (s *Spec) Merge(override *Spec) *Spec {
+	merged := &Spec{Scopes: make(map[string]string), Fields: make(map[string]Rules)}
+	for _, spec := range []*Spec{s, override} {
+		if spec == nil {
+			continue
+		}
+		for name, sel := range spec.Scopes {
+			merged.Scopes[name] = sel
+		}
+		for name, rules := range spec.Fields {
+			copied := make(Rules, 0, len(rules))
+			for _, rule := range rules {
+				if rule != nil {
+					c := *rule
+					c.re, c.post = nil, nil
+					copied = append(copied, &c)
+				}
+			}
+			merged.Fields[name] = copied
+		}
+	}
+	return merged
+}
+
+// Compile 检查规则并编译正则与后处理，prototype 为抽取目标的结构体指针，
+// procs 为后处理函数，与 Builtins 同名时覆盖内置函数
+func (s *Spec) Compile(prototype interface{}, procs map[string]Processor) error {
+	fields, err := structFields(prototype)
+	if err != nil {
+		return err
+	}
+	s.fields = make(map[string]int, len(s.Fields))
+
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("field %s: no such field", name)
+		}
+		s.fields[name] = f.index
+		if len(s.Fields[name]) == 0 {
+			return fmt.Errorf("field %s: no rule", name)
+		}
+		for i, rule := range s.Fields[name] {
+			if err := s.compileRule(rule, f.kind, procs); err != nil {
+				return fmt.Errorf("field %s rule %d: %s", name, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Spec) compileRule(rule *Rule, kind fieldKind, procs map[string]Processor) (err error) {
+	if rule == nil || rule.Selector == "" {
+		return fmt.Errorf("empty selector")
+	}
+	if _, ok := s.Scopes[rule.Scope]; rule.Scope != "" && !ok {
+		return fmt.Errorf("unknown scope %q", rule.Scope)
+	}
+	if rule.Node != "" && rule.Node != "first" && rule.Node != "last" {
+		return fmt.Errorf("node should be first or last, got %q", rule.Node)
+	}
+	if rule.List != (kind == kindList) {
+		return fmt.Errorf("list rule is required by and only by list field")
+	}
+	if rule.Regex != "" {
+		if rule.re, err = regexp.Compile(rule.Regex); err != nil {
+			return err
+		}
+	}
+	rule.post = rule.post[:0]
+	for _, p := range rule.Post {
+		name, arg := p, ""
+		if i := strings.IndexByte(p, ':'); i > 0 {
+			name, arg = p[:i], p[i+1:]
+		}
+		fn, ok := procs[name]
+		if !ok {
+			fn, ok = Builtins[name]
+		}
+		if !ok {
+			return fmt.Errorf("unknown post processor %q", name)
+		}
+		rule.post = append(rule.post, step{name, arg, fn})
+	}
+	return nil
+}
+
+// Load 读取YAML或JSON格式的规则覆盖文件，文件以来源为键，如 `wdj: {fields: ...}`
+func Load(filename string) (map[string]*Spec, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var specs map[string]*Spec
+	if err = yaml.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return specs, nil
+}
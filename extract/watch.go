@@ -0,0 +1,32 @@
+package extract
+
+import (
+	"os"
+	"time"
+	"context"
+)
+
+// Watch 每隔 interval 检查文件的修改时间与大小，文件变化后调用 reload，ctx 结束时返回
+// 启动时不调用 reload，文件被删除后重新出现也视为变化
+func Watch(ctx context.Context, filename string, interval time.Duration, reload func()) {
+	stat := func() (time.Time, int64) {
+		if fi, err := os.Stat(filename); err == nil {
+			return fi.ModTime(), fi.Size()
+		}
+		return time.Time{}, -1
+	}
+	mtime, size := stat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if t, n := stat(); !t.Equal(mtime) || n != size {
+				mtime, size = t, n
+				reload()
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package fill
+
+import (
+	"regexp"
+	"reflect"
+	"unicode"
+)
+
+// String、Int、Float、List 在 dst 为零值时以 src 填充，用于以备用来源补全未取到的字段
+func String(dst *string, src string) {
+	if *dst == "" {
+		*dst = src
+	}
+}
+
+func Int(dst *int64, src int64) {
+	if *dst == 0 {
+		*dst = src
+	}
+}
+
+func Float(dst *float64, src float64) {
+	if *dst == 0 {
+		*dst = src
+	}
+}
+
+func List(dst *[]string, src []string) {
+	if len(*dst) == 0 {
+		*dst = src
+	}
+}
+
+// Fields 返回结构体各导出字段是否被填充，键为下划线风格的字段名(与数据库列名一致)，
+// 零值与空切片、空映射视为未填充
+func Fields(v interface{}) map[string]bool {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	rt := rv.Type()
+	filled := make(map[string]bool, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		if f := rt.Field(i); f.PkgPath == "" {
+			filled[Underscore(f.Name)] = !isZero(rv.Field(i))
+		}
+	}
+	return filled
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// Underscore 将驼峰式的字段名转为下划线风格: InstallCnt => install_cnt, AppID => app_id, URL => url
+func Underscore(name string) string {
+	runes := []rune(name)
+	var buf []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// 单词边界：前一个为小写，或处于连续大写的末尾且后一个为小写
+			if i > 0 && (unicode.IsLower(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])) {
+				buf = append(buf, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		buf = append(buf, r)
+	}
+	return string(buf)
+}
+
+var (
+	pVersion = regexp.MustCompile(`^[vV]?\d+(\.\d+)*([.\-_+ ]?[0-9A-Za-z]+)*$`)
+	pSize    = regexp.MustCompile(`(?i)^\d+(\.\d+)?\s*[KMG]B?$`)
+)
+
+// IsVersion 判断是否像一个版本号，如 `6.5.10`、`V2.1.0-beta`，
+// 用于发现选择器取错位置，例如取到了日期 `2017年07月29日` 或大小 `34.72MB`
+func IsVersion(s string) bool {
+	return pVersion.MatchString(s) && !pSize.MatchString(s)
+}
@@ -0,0 +1,101 @@
+package fill
+
+import (
+	"time"
+	"testing"
+)
+
+func TestUnderscore(t *testing.T) {
+	for name, want := range map[string]string{
+		"ID": "id", "URL": "url", "AppID": "app_id", "InstallCnt": "install_cnt",
+		"ReleaseTime": "release_time", "HTTPServer": "http_server", "Name": "name",
+	} {
+		if got := Underscore(name); got != want {
+			t.Errorf("Underscore(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	type app struct {
+		ID      string
+		Size    int64
+		Tags    []string
+		Time    time.Time
+		private string
+	}
+	got := Fields(&app{ID: "a", Tags: []string{}, private: "x"})
+	want := map[string]bool{"id": true, "size": false, "tags": false, "time": false}
+	if len(got) != len(want) {
+		t.Fatalf("Fields = %v", got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Fields[%s] = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestIsVersion(t *testing.T) {
+	for s, want := range map[string]bool{
+		"6.5.10": true, "V2.1.0-beta": true, "1.0 build 20": true, "10": true,
+		"": false, "2017年07月29日": false, "34.72MB": false, "腾讯": false, "800KB": false,
+	} {
+		if IsVersion(s) != want {
+			t.Errorf("IsVersion(%q) = %v, want %v", s, !want, want)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker(10, 0.2)
+	observe := func(n int, version bool) (alerts []Alert) {
+		for i := 0; i < n; i++ {
+			alerts = append(alerts, tr.Observe("wdj", map[string]bool{"version": version, "name": true})...)
+		}
+		return
+	}
+
+	if alerts := observe(100, true); len(alerts) != 0 {
+		t.Fatalf("alerts during warmup and steady state: %v", alerts)
+	}
+	alerts := observe(3, false)
+	if len(alerts) != 1 || alerts[0].Field != "version" || alerts[0].Recovered || alerts[0].Baseline < 0.99 {
+		t.Fatalf("drift alerts = %+v", alerts)
+	}
+	// 异常期间不重复告警，基线保持不变
+	if alerts = observe(20, false); len(alerts) != 0 {
+		t.Fatalf("repeated alerts = %+v", alerts)
+	}
+	alerts = observe(10, true)
+	if len(alerts) != 1 || !alerts[0].Recovered {
+		t.Fatalf("recover alerts = %+v", alerts)
+	}
+
+	rates := tr.Rates()
+	if len(rates) != 2 || rates[0].Field != "name" || rates[0].Rate != 1 || rates[1].Samples != 133 {
+		t.Errorf("rates = %+v", rates)
+	}
+
+	// 重启后首个窗口填满即可发现异常
+	tr = NewTracker(10, 0.2)
+	observe(10, true)
+	if alerts = observe(3, false); len(alerts) != 1 || alerts[0].Samples != 13 {
+		t.Errorf("drift alerts after first window = %+v", alerts)
+	}
+
+	// 恢复的基线在首个窗口内保持不变，窗口填满即与之比较
+	tr = NewTracker(10, 0.2)
+	tr.Seed("wdj", "version", 1)
+	tr.Seed("wdj", "name", 1)
+	if rates := tr.Rates(); len(rates) != 0 {
+		t.Errorf("rates of seeded fields = %+v", rates)
+	}
+	if alerts = observe(10, false); len(alerts) != 1 || alerts[0].Field != "version" || alerts[0].Baseline != 1 {
+		t.Errorf("drift alerts against seeded baseline = %+v", alerts)
+	}
+	tr.Seed("wdj", "version", 0)
+	if rates := tr.Rates(); rates[1].Baseline != 1 {
+		t.Errorf("seed overrides observed field: %+v", rates)
+	}
+}
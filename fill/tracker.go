@@ -0,0 +1,161 @@
+package fill
+
+import (
+	"sort"
+	"sync"
+)
+
+// Rate 是一个字段的填充率
+type Rate struct {
+	Source   string  `json:"source"`
+	Field    string  `json:"field"`
+	Rate     float64 `json:"rate"`     // 最近 Window 次解析的填充率
+	Baseline float64 `json:"baseline"` // 长期填充率，随解析缓慢更新，字段异常期间不更新
+	Samples  int     `json:"samples"`  // 累计解析次数
+	Drifted  bool    `json:"drifted"`  // 填充率是否低于基线
+}
+
+// Alert 在字段的填充率跌破基线或恢复时产生
+type Alert struct {
+	Rate
+	Recovered bool // true 表示已恢复
+}
+
+// fieldStat 是一个字段的滚动统计
+type fieldStat struct {
+	window   []bool // 环形缓冲区
+	next     int
+	filled   int
+	samples  int
+	baseline float64
+	drifted  bool
+	seeded   bool // 基线来自 Seed，首个窗口内不以当前填充率替换
+}
+
+// Tracker 按来源与字段统计最近若干次解析的填充率，并与基线比较，可并发使用
+// 基线为填充率的指数移动平均，在 Warmup 次解析之后才开始比较；
+// 当最近填充率低于 基线-Tolerance 时产生告警，回到该阈值以上时产生恢复通知。
+// 统计只保存在内存中，调用方可用 Rates 保存基线，重启后用 Seed 恢复
+type Tracker struct {
+	Window    int     // 滚动窗口大小
+	Warmup    int     // 开始比较前的最少解析次数，不小于 Window
+	Tolerance float64 // 允许低于基线的幅度
+	Alpha     float64 // 基线的更新速度
+
+	mu    sync.Mutex
+	stats map[string]map[string]*fieldStat
+}
+
+// NewTracker 返回窗口为window、容忍度为tolerance的 Tracker。
+// 为避免重启后长时间无法发现异常，基线在首个窗口填满后即开始生效
+func NewTracker(window int, tolerance float64) *Tracker {
+	if window <= 0 {
+		window = 1
+	}
+	return &Tracker{Window: window, Warmup: window, Tolerance: tolerance, Alpha: 1 / float64(window*10)}
+}
+
+// Observe 记录一次解析的字段填充情况，返回本次产生的告警与恢复通知
+func (t *Tracker) Observe(source string, filled map[string]bool) (alerts []Alert) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stats == nil {
+		t.stats = make(map[string]map[string]*fieldStat)
+	}
+	fields := t.stats[source]
+	if fields == nil {
+		fields = make(map[string]*fieldStat)
+		t.stats[source] = fields
+	}
+
+	for field, ok := range filled {
+		s := fields[field]
+		if s == nil {
+			s = &fieldStat{window: make([]bool, 0, t.Window)}
+			fields[field] = s
+		}
+		if len(s.window) < t.Window {
+			s.window = append(s.window, ok)
+		} else {
+			if s.window[s.next] {
+				s.filled--
+			}
+			s.window[s.next] = ok
+			s.next = (s.next + 1) % t.Window
+		}
+		if ok {
+			s.filled++
+		}
+		s.samples++
+
+		rate := float64(s.filled) / float64(len(s.window))
+		if s.samples <= len(s.window) && !s.seeded {
+			s.baseline = rate // 首个窗口内以当前填充率为基线
+		}
+		if s.samples < t.Warmup {
+			if s.samples > len(s.window) && !s.seeded {
+				s.baseline += t.Alpha * (rate - s.baseline)
+			}
+			continue
+		}
+
+		drifted := rate < s.baseline-t.Tolerance
+		if !drifted {
+			s.baseline += t.Alpha * (rate - s.baseline)
+		}
+		if drifted != s.drifted {
+			s.drifted = drifted
+			alerts = append(alerts, Alert{t.rate(source, field, s), !drifted})
+		}
+	}
+	return
+}
+
+// Seed 设置字段的初始基线，如上次运行保存的基线或预期的填充率。
+// 该字段在首个窗口填满后即与此基线比较，而不是以首个窗口的填充率为基线；已有统计的字段不受影响
+func (t *Tracker) Seed(source, field string, baseline float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stats == nil {
+		t.stats = make(map[string]map[string]*fieldStat)
+	}
+	fields := t.stats[source]
+	if fields == nil {
+		fields = make(map[string]*fieldStat)
+		t.stats[source] = fields
+	}
+	if s := fields[field]; s != nil && s.samples > 0 {
+		return
+	}
+	fields[field] = &fieldStat{window: make([]bool, 0, t.Window), baseline: baseline, seeded: true}
+}
+
+func (t *Tracker) rate(source, field string, s *fieldStat) Rate {
+	r := Rate{Source: source, Field: field, Baseline: s.baseline, Samples: s.samples, Drifted: s.drifted}
+	if len(s.window) > 0 {
+		r.Rate = float64(s.filled) / float64(len(s.window))
+	}
+	return r
+}
+
+// Rates 返回所有字段的填充率，按来源与字段名排序，只有 Seed 而尚未解析过的字段不返回
+func (t *Tracker) Rates() []Rate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var rates []Rate
+	for source, fields := range t.stats {
+		for field, s := range fields {
+			if s.samples == 0 {
+				continue
+			}
+			rates = append(rates, t.rate(source, field, s))
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Source != rates[j].Source {
+			return rates[i].Source < rates[j].Source
+		}
+		return rates[i].Field < rates[j].Field
+	})
+	return rates
+}
@@ -0,0 +1,160 @@
+package jsobj
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldErrors 为 Decode 中无法转换的字段，键为对象中的字段名，其余字段已正常写入
+type FieldErrors map[string]error
+
+// Error 实现 error，按字段名排序
+func (e FieldErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	msgs := make([]string, len(keys))
+	for i, key := range keys {
+		msgs[i] = key + ": " + e[key].Error()
+	}
+	return "jsobj: " + strings.Join(msgs, "; ")
+}
+
+// Decode 将 Parse 得到的对象写入结构体 v，字段名由 `js:"appId"` 标签指定，无标签的字段忽略
+// 页面脚本常把数字与布尔值写成字符串，如 `apkCode : "1080"`，因此 int64、float64、bool 字段
+// 也接受字符串，空串与 null 视为零值。值无法转换时不会静默置零：该字段保持原值并跳过，
+// 其余字段照常写入，最后返回 FieldErrors
+func Decode(obj interface{}, v interface{}) error {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("jsobj: expect object, got %T", obj)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("jsobj: expect pointer to struct, got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	errs := FieldErrors{}
+	for i := 0; i < rt.NumField(); i++ {
+		key := rt.Field(i).Tag.Get("js")
+		if key == "" || key == "-" {
+			continue
+		}
+		val, ok := m[key]
+		if !ok || val == nil {
+			continue
+		}
+		if err := set(rv.Field(i), val); err != nil {
+			errs[key] = err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Unmarshal 在脚本中找到变量 name 的值并写入结构体 v，见 Var 与 Decode
+func Unmarshal(script, name string, v interface{}) error {
+	obj, err := Var(script, name)
+	if err != nil {
+		return err
+	}
+	return Decode(obj, v)
+}
+
+// set 将一个值转换后写入字段
+func set(field reflect.Value, val interface{}) error {
+	s, isString := val.(string)
+	s = strings.TrimSpace(s)
+	switch field.Kind() {
+	case reflect.String:
+		switch val := val.(type) {
+		case string:
+			field.SetString(val)
+		case Number:
+			field.SetString(string(val))
+		case bool:
+			field.SetString(strconv.FormatBool(val))
+		default:
+			return fmt.Errorf("cannot use %T as string", val)
+		}
+	case reflect.Int64, reflect.Int:
+		n, ok := val.(Number)
+		if isString {
+			if s == "" {
+				return nil
+			}
+			n, ok = Number(s), true
+		}
+		if !ok {
+			return fmt.Errorf("cannot use %T as int", val)
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return fmt.Errorf("invalid int %q", string(n))
+		}
+		field.SetInt(i)
+	case reflect.Float64:
+		n, ok := val.(Number)
+		if isString {
+			if s == "" {
+				return nil
+			}
+			n, ok = Number(s), true
+		}
+		if !ok {
+			return fmt.Errorf("cannot use %T as float", val)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid float %q", string(n))
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		switch val := val.(type) {
+		case bool:
+			field.SetBool(val)
+		case string:
+			if s == "" {
+				return nil
+			}
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("invalid bool %q", s)
+			}
+			field.SetBool(b)
+		case Number:
+			field.SetBool(val != "0")
+		default:
+			return fmt.Errorf("cannot use %T as bool", val)
+		}
+	case reflect.Slice, reflect.Map, reflect.Interface:
+		if arr, ok := val.([]interface{}); ok && field.Type() == reflect.TypeOf([]string(nil)) {
+			list := make([]string, 0, len(arr))
+			for _, item := range arr {
+				s, ok := item.(string)
+				if !ok {
+					return fmt.Errorf("cannot use %T as string", item)
+				}
+				list = append(list, s)
+			}
+			field.Set(reflect.ValueOf(list))
+			return nil
+		}
+		rv := reflect.ValueOf(val)
+		if !rv.Type().AssignableTo(field.Type()) {
+			return fmt.Errorf("cannot use %T as %s", val, field.Type())
+		}
+		field.Set(rv)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
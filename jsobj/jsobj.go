@@ -0,0 +1,324 @@
+package jsobj
+
+import (
+	"fmt"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrNotFound 表示脚本中没有找到指定变量
+var ErrNotFound = errors.New("jsobj: variable not found")
+
+// Number 为数字字面量的原文，避免大整数经过 float64 丢失精度
+type Number string
+
+// Int64 按整数解析，带小数时截断
+func (n Number) Int64() (int64, error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	return int64(f), err
+}
+
+// Float64 按浮点数解析
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// SyntaxError 为字面量的语法错误，Offset 为出错位置的字节偏移
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("jsobj: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Parse 解析一个 JavaScript 字面量，如 `{appId : "10910", tags: ['a', 'b'],}`
+// 对象转为 map[string]interface{}，数组转为 []interface{}，字符串为 string，数字为 Number，
+// true/false 为 bool，null/undefined 为 nil。键可以是标识符、字符串或数字，允许注释与末尾逗号。
+// 字面量之后的内容被忽略，如语句结尾的 `;`
+func Parse(src string) (interface{}, error) {
+	p := &parser{src: src}
+	return p.value()
+}
+
+// Var 在脚本中找到形如 `name = {...}` 或 `name: {...}` 的赋值并解析其值
+func Var(script, name string) (interface{}, error) {
+	for i := 0; ; {
+		j := strings.Index(script[i:], name)
+		if j < 0 {
+			return nil, ErrNotFound
+		}
+		start, end := i+j, i+j+len(name)
+		i = end
+		// 须为完整的标识符
+		if r, _ := utf8.DecodeLastRuneInString(script[:start]); start > 0 && isIdent(r) {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(script[end:]); end < len(script) && isIdent(r) {
+			continue
+		}
+		p := &parser{src: script, pos: end}
+		p.skip()
+		if p.pos >= len(p.src) || p.src[p.pos] != '=' && p.src[p.pos] != ':' ||
+			p.src[p.pos] == '=' && strings.HasPrefix(p.src[p.pos:], "==") {
+			continue
+		}
+		p.pos++
+		return p.value()
+	}
+}
+
+// parser 为递归下降解析器
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{p.pos, fmt.Sprintf(format, args...)}
+}
+
+// skip 跳过空白与注释
+func (p *parser) skip() {
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		switch {
+		case unicode.IsSpace(r):
+			p.pos += size
+		case strings.HasPrefix(p.src[p.pos:], "//"):
+			if i := strings.IndexByte(p.src[p.pos:], '\n'); i >= 0 {
+				p.pos += i + 1
+			} else {
+				p.pos = len(p.src)
+			}
+		case strings.HasPrefix(p.src[p.pos:], "/*"):
+			if i := strings.Index(p.src[p.pos+2:], "*/"); i >= 0 {
+				p.pos += i + 4
+			} else {
+				p.pos = len(p.src)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) value() (interface{}, error) {
+	p.skip()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"' || c == '\'':
+		return p.string()
+	case c == '-' || c == '+' || c == '.' || '0' <= c && c <= '9':
+		return p.number()
+	}
+	start := p.pos
+	switch word := p.ident(); word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null", "undefined":
+		return nil, nil
+	default:
+		p.pos = start
+		return nil, p.errorf("unsupported value %q", word)
+	}
+}
+
+func (p *parser) object() (map[string]interface{}, error) {
+	p.pos++ // {
+	obj := make(map[string]interface{})
+	for {
+		p.skip()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			return obj, nil
+		}
+
+		var key string
+		switch c := p.src[p.pos]; {
+		case c == '"' || c == '\'':
+			s, err := p.string()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case '0' <= c && c <= '9':
+			n, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+			key = string(n)
+		default:
+			if key = p.ident(); key == "" {
+				return nil, p.errorf("invalid object key")
+			}
+		}
+
+		p.skip()
+		if p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			return nil, p.errorf("expect ':' after key %q", key)
+		}
+		p.pos++
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = v
+
+		p.skip()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		} else if p.pos < len(p.src) && p.src[p.pos] != '}' {
+			return nil, p.errorf("expect ',' or '}' after value of %q", key)
+		}
+	}
+}
+
+func (p *parser) array() ([]interface{}, error) {
+	p.pos++ // [
+	arr := []interface{}{}
+	for {
+		p.skip()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			return arr, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+
+		p.skip()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+		} else if p.pos < len(p.src) && p.src[p.pos] != ']' {
+			return nil, p.errorf("expect ',' or ']' in array")
+		}
+	}
+}
+
+// string 解析单引号或双引号字符串，支持常见转义
+func (p *parser) string() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+	var buf bytes.Buffer
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return buf.String(), nil
+		case c == '\n':
+			return "", p.errorf("newline in string")
+		case c != '\\':
+			buf.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		// 转义
+		p.pos++
+		if p.pos >= len(p.src) {
+			break
+		}
+		c = p.src[p.pos]
+		p.pos++
+		switch c {
+		case 'n':
+			buf.WriteByte('\n')
+		case 't':
+			buf.WriteByte('\t')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'b':
+			buf.WriteByte('\b')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'v':
+			buf.WriteByte('\v')
+		case '0':
+			buf.WriteByte(0)
+		case 'x', 'u':
+			n := 2
+			if c == 'u' {
+				n = 4
+			}
+			if p.pos+n > len(p.src) {
+				return "", p.errorf("invalid escape \\%c", c)
+			}
+			code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+			if err != nil {
+				return "", p.errorf("invalid escape \\%c", c)
+			}
+			p.pos += n
+			buf.WriteRune(rune(code))
+		case '\n':
+			// 行尾的反斜杠为续行
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) number() (Number, error) {
+	start := p.pos
+	if c := p.src[p.pos]; c == '-' || c == '+' {
+		p.pos++
+	}
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if '0' <= c && c <= '9' || c == '.' || c == 'e' || c == 'E' ||
+			(c == '-' || c == '+') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	n := Number(strings.TrimPrefix(p.src[start:p.pos], "+"))
+	if _, err := n.Float64(); err != nil {
+		p.pos = start
+		return "", p.errorf("invalid number %q", string(n))
+	}
+	return n, nil
+}
+
+// ident 读取一个标识符，不是标识符时返回空串
+func (p *parser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !isIdent(r) || p.pos == start && unicode.IsDigit(r) {
+			break
+		}
+		p.pos += size
+	}
+	return p.src[start:p.pos]
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
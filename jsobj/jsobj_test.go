@@ -0,0 +1,103 @@
+package jsobj
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testScript = `
+// 页面数据
+var other = 1, appDetailDataX = {appId: "0"};
+var appDetailData = {
+	orgame : "1",
+	apkName : "com.tencent.mm",
+	apkCode : "1080",
+	'appId' : "10910",
+	"appName": "微信",
+	iconUrl:"http://pp.myapp.com/ma_icon/0/icon_10910_1501491243/96",
+	appScore:"",
+	score: 4.5, /* 块注释 */
+	tags: ['社交', "通讯",],
+	nested: {a: [1, -2.5e3, true, null, undefined]},
+	tipsUpDown:"false",
+};
+if (appDetailData == null) {}
+`
+
+func TestVar(t *testing.T) {
+	v, err := Var(testScript, "appDetailData")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := v.(map[string]interface{})
+	want := map[string]interface{}{
+		"orgame": "1", "apkName": "com.tencent.mm", "apkCode": "1080", "appId": "10910", "appName": "微信",
+		"iconUrl": "http://pp.myapp.com/ma_icon/0/icon_10910_1501491243/96", "appScore": "", "score": Number("4.5"),
+		"tags":       []interface{}{"社交", "通讯"},
+		"nested":     map[string]interface{}{"a": []interface{}{Number("1"), Number("-2.5e3"), true, nil, nil}},
+		"tipsUpDown": "false",
+	}
+	if !reflect.DeepEqual(obj, want) {
+		t.Errorf("got %#v\nwant %#v", obj, want)
+	}
+
+	if _, err := Var(testScript, "missing"); err != ErrNotFound {
+		t.Errorf("missing variable: err = %v", err)
+	}
+	if _, err := Var("appDetailData == null", "appDetailData"); err != ErrNotFound {
+		t.Errorf("comparison is not assignment: err = %v", err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, src := range []string{
+		``, `{`, `{a 1}`, `{a: 1 b: 2}`, `{a: 'x}`, "{a: \"x\ny\"}", `[1 2]`, `{a: foo()}`, `{a: -}`, `{a: "\u12"}`,
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("%q should fail", src)
+		} else if _, ok := err.(*SyntaxError); !ok {
+			t.Errorf("%q: err = %T, want *SyntaxError", src, err)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var data struct {
+		ApkName  string   `js:"apkName"`
+		ApkCode  int64    `js:"apkCode"`
+		AppID    int64    `js:"appId"`
+		AppScore float64  `js:"appScore"`
+		Score    float64  `js:"score"`
+		Tips     bool     `js:"tipsUpDown"`
+		Tags     []string `js:"tags"`
+		Missing  int64    `js:"missing"`
+		Ignored  string
+	}
+	data.Missing = 7
+	if err := Unmarshal(testScript, "appDetailData", &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.ApkName != "com.tencent.mm" || data.ApkCode != 1080 || data.AppID != 10910 || data.AppScore != 0 ||
+		data.Score != 4.5 || data.Tips || len(data.Tags) != 2 || data.Missing != 7 {
+		t.Errorf("got %+v", data)
+	}
+
+	var bad struct {
+		AppID   int64 `js:"appId"`
+		ApkCode int64 `js:"apkCode"`
+	}
+	bad.AppID = 1
+	err := Unmarshal(`var d = {appId: "10910abc", apkCode: "12"}`, "d", &bad)
+	if errs, ok := err.(FieldErrors); !ok || len(errs) != 1 || errs["appId"] == nil {
+		t.Errorf("invalid int should fail instead of zeroing, got %v", err)
+	}
+	if bad.AppID != 1 || bad.ApkCode != 12 {
+		t.Errorf("invalid field should be skipped and others decoded, got %+v", bad)
+	}
+	if err := Decode([]interface{}{}, &bad); err == nil {
+		t.Error("non-object should fail")
+	}
+	if err := Decode(map[string]interface{}{}, bad); err == nil {
+		t.Error("non-pointer should fail")
+	}
+}
@@ -0,0 +1,82 @@
+package norm
+
+import (
+	"time"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Location 为页面上日期所用的时区，即北京时间。系统缺少时区数据时使用固定的 UTC+8
+var Location = loadLocation()
+
+func loadLocation() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Shanghai"); err == nil {
+		return loc
+	}
+	return time.FixedZone("CST", 8*60*60)
+}
+
+var (
+	// `2017年07月05日`、`2017年7月5日 15:04`、`2017-07-05 15:04:05`、`2017/7/5`、`2017.07.05`
+	pDate = regexp.MustCompile(`^(\d{4})\s*[年./-]\s*(\d{1,2})\s*[月./-]\s*(\d{1,2})\s*日?(?:\s*(\d{1,2})\s*[:时]\s*(\d{1,2})\s*(?:[:分]\s*(\d{1,2})\s*秒?|分)?)?$`)
+	// `20170705`，旧数据中去掉分隔符的日期
+	pCompact = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})$`)
+	// `今天 15:04`、`昨天`、`前天 08:30`
+	pDay = regexp.MustCompile(`^(今天|昨天|前天)(?:\s*(\d{1,2}):(\d{1,2}))?$`)
+	// `刚刚`、`5分钟前`、`3小时前`、`2天前`
+	pAgo = regexp.MustCompile(`^(\d+)\s*(秒|分钟|小时|天)前$`)
+)
+
+// Date 解析页面上的日期，未注明时区的按 Location 解析，见 DateAt
+func Date(s string) (time.Time, bool) {
+	return DateAt(s, time.Now())
+}
+
+// DateAt 解析日期，相对日期如 `昨天`、`3小时前` 以 now 为基准。支持 RFC3339、
+// `2017年07月05日`、`2017-07-05 15:04:05`、`2017/7/5`、`2017.07.05` 与 `20170705`，非法日期如 2 月 30 日返回 false
+func DateAt(s string, now time.Time) (time.Time, bool) {
+	s = strings.TrimSpace(width.Replace(s))
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	if m := pDate.FindStringSubmatch(s); m != nil {
+		return date(m[1:])
+	}
+	if m := pCompact.FindStringSubmatch(s); m != nil {
+		return date(m[1:])
+	}
+	now = now.In(Location)
+	if s == "刚刚" {
+		return now, true
+	}
+	if m := pAgo.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n > 100000 {
+			return time.Time{}, false
+		}
+		unit := map[string]time.Duration{"秒": time.Second, "分钟": time.Minute, "小时": time.Hour, "天": 24 * time.Hour}[m[2]]
+		return now.Add(-time.Duration(n) * unit), true
+	}
+	if m := pDay.FindStringSubmatch(s); m != nil {
+		day := now.AddDate(0, 0, -map[string]int{"今天": 0, "昨天": 1, "前天": 2}[m[1]])
+		return date([]string{strconv.Itoa(day.Year()), strconv.Itoa(int(day.Month())), strconv.Itoa(day.Day()), m[2], m[3]})
+	}
+	return time.Time{}, false
+}
+
+// date 由年、月、日、时、分、秒构造时间，缺少的时分秒为 0，超出范围时返回 false
+func date(fields []string) (time.Time, bool) {
+	var v [6]int
+	for i, f := range fields {
+		if f != "" {
+			v[i], _ = strconv.Atoi(f)
+		}
+	}
+	year, month, day, hour, min, sec := v[0], time.Month(v[1]), v[2], v[3], v[4], v[5]
+	t := time.Date(year, month, day, hour, min, sec, 0, Location)
+	if t.Year() != year || t.Month() != month || t.Day() != day || t.Hour() != hour || t.Minute() != min || t.Second() != sec {
+		return time.Time{}, false
+	}
+	return t, true
+}
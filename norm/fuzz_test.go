@@ -0,0 +1,86 @@
+package norm
+
+import (
+	"time"
+	"strconv"
+	"testing"
+)
+
+// 模糊测试检查任意输入不会 panic，结果在值域内，且规范的写法可以往返
+// 日期只检查 2000 年以后，避免夏令时切换时有歧义的本地时间
+// 运行：go test -fuzz FuzzNumber ./norm
+
+func FuzzNumber(f *testing.F) {
+	for _, s := range []string{"1.28亿", "5万+", "1,234次", "一亿二千万", "十二", "３千", "", "亿亿亿"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		n, ok := Number(s)
+		if !ok {
+			if n != 0 {
+				t.Errorf("Number(%q) = %d, false", s, n)
+			}
+			return
+		}
+		if n < 0 {
+			t.Errorf("Number(%q) = %d, want non-negative", s, n)
+		}
+		if back, ok := Number(strconv.FormatInt(n, 10)); !ok || back != n {
+			t.Errorf("Number(%d) = %d, %v", n, back, ok)
+		}
+	})
+}
+
+func FuzzSize(f *testing.F) {
+	for _, s := range []string{"128k", "25 MB", "43.08MB", "1,024KB", "7E", "", "MB"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		n, ok := Size(s)
+		if ok && n < 0 || !ok && n != 0 {
+			t.Errorf("Size(%q) = %d, %v", s, n, ok)
+		}
+		if ok {
+			if back, ok := Size(strconv.FormatInt(n, 10) + "B"); !ok || back != n {
+				t.Errorf("Size(%dB) = %d, %v", n, back, ok)
+			}
+		}
+	})
+}
+
+func FuzzRating(f *testing.F) {
+	for _, s := range []string{"97.00%", "4.3分", "4/5", "NaN", "Inf", "1e2", ""} {
+		f.Add(s, 5.0)
+	}
+	f.Fuzz(func(t *testing.T, s string, best float64) {
+		if n, ok := Percent(s); n < 0 || n > 100 || !ok && n != 0 {
+			t.Errorf("Percent(%q) = %d, %v", s, n, ok)
+		}
+		if n, ok := Stars(s, best); n < 0 || n > 100 || !ok && n != 0 {
+			t.Errorf("Stars(%q, %v) = %d, %v", s, best, n, ok)
+		}
+	})
+}
+
+func FuzzDate(f *testing.F) {
+	for _, s := range []string{"2017年07月05日", "2017-07-05 15:04:05", "20170705", "昨天 08:30", "3小时前", "2017-07-05T15:04:05Z", ""} {
+		f.Add(s)
+	}
+	now := time.Date(2017, 7, 5, 10, 30, 0, 0, Location)
+	f.Fuzz(func(t *testing.T, s string) {
+		d, ok := DateAt(s, now)
+		if !ok {
+			if !d.IsZero() {
+				t.Errorf("DateAt(%q) = %v, false", s, d)
+			}
+			return
+		}
+		if d = d.In(Location); d.Year() < 2000 || d.Year() > 9999 || d.Nanosecond() != 0 {
+			return
+		}
+		formatted := d.Format("2006-01-02 15:04:05")
+		if back, ok := DateAt(formatted, now); !ok || !back.Equal(d) {
+			t.Errorf("DateAt(%q) = %v, %v; want %v", formatted, back, ok, d)
+		}
+	})
+}
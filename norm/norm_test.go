@@ -0,0 +1,206 @@
+package norm
+
+import (
+	"time"
+	"testing"
+)
+
+func TestNumber(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"1234", 1234, true},
+		{" 1,234 ", 1234, true},
+		{"1，234，567", 1234567, true},
+		{"1.28亿", 128000000, true},
+		{"21.5亿次", 2150000000, true},
+		{"5万+", 50000, true},
+		{"10万余人", 100000, true},
+		{"3千", 3000, true},
+		{"1.5千万", 15000000, true},
+		{"2百万", 2000000, true},
+		{"1.2万亿", 1200000000000, true},
+		{"３．５万", 35000, true},
+		{"1.2k", 1200, true},
+		{"8W", 80000, true},
+		{"1.23456万", 12345, true},
+		{"十二", 12, true},
+		{"一百零五", 105, true},
+		{"三千", 3000, true},
+		{"二十万", 200000, true},
+		{"一万二千", 12000, true},
+		{"一亿二千万", 120000000, true},
+		{"两万亿", 2000000000000, true},
+		{"", 0, false},
+		{"万", 0, false},
+		{"+", 0, false},
+		{"暂无", 0, false},
+		{"-5", 0, false},
+		{"1.2.3", 0, false},
+		{"1e5", 0, false},
+		{".", 0, false},
+		{"99999999999999999999", 0, false},
+		{"99999999999亿", 0, false},
+		{"一亿亿亿", 0, false},
+	}
+	for _, c := range cases {
+		if got, ok := Number(c.in); got != c.want || ok != c.ok {
+			t.Errorf("Number(%q) = %d, %v; want %d, %v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"512", 512, true},
+		{"512B", 512, true},
+		{"128k", 128 << 10, true},
+		{"25 MB", 25 << 20, true},
+		{"43.08MB", 45172654, true},
+		{"1,024KB", 1 << 20, true},
+		{"1.5 GiB", 3 << 29, true},
+		{"2兆", 2 << 20, true},
+		{"１．５ｍｂ", 0, false},
+		{"7E", 7 << 60, true},
+		{"8E", 0, false},
+		{"", 0, false},
+		{"MB", 0, false},
+		{"25 XB", 0, false},
+		{"-1MB", 0, false},
+	}
+	for _, c := range cases {
+		if got, ok := Size(c.in); got != c.want || ok != c.ok {
+			t.Errorf("Size(%q) = %d, %v; want %d, %v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"97.00%", 97, true},
+		{"97％", 97, true},
+		{"85.9", 85, true},
+		{"好评率 85.5%", 85, true},
+		{"100%", 100, true},
+		{"0%", 0, true},
+		{"100.1%", 0, false},
+		{"NaN", 0, false},
+		{"Inf%", 0, false},
+		{"1e2%", 0, false},
+		{"", 0, false},
+		{"暂无", 0, false},
+	}
+	for _, c := range cases {
+		if got, ok := Percent(c.in); got != c.want || ok != c.ok {
+			t.Errorf("Percent(%q) = %d, %v; want %d, %v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestStars(t *testing.T) {
+	cases := []struct {
+		in   string
+		best float64
+		want int64
+		ok   bool
+	}{
+		{"4.3", 5, 86, true},
+		{"4.1分", 0, 82, true},
+		{"3星", 5, 60, true},
+		{"8.8", 10, 88, true},
+		{"4 / 5", 10, 80, true},
+		{"５", 5, 100, true},
+		{"5.1", 5, 0, false},
+		{"4/0", 5, 0, false},
+		{"-1", 5, 0, false},
+		{"", 5, 0, false},
+	}
+	for _, c := range cases {
+		if got, ok := Stars(c.in, c.best); got != c.want || ok != c.ok {
+			t.Errorf("Stars(%q, %v) = %d, %v; want %d, %v", c.in, c.best, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	at := func(y int, m time.Month, d, h, min, s int) time.Time {
+		return time.Date(y, m, d, h, min, s, 0, Location)
+	}
+	now := at(2017, 7, 5, 10, 30, 0)
+	cases := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"2017年07月05日", at(2017, 7, 5, 0, 0, 0), true},
+		{"2017年7月5日", at(2017, 7, 5, 0, 0, 0), true},
+		{"2017 年 7 月 5 日 15:04", at(2017, 7, 5, 15, 4, 0), true},
+		{"2017年7月5日15时4分", at(2017, 7, 5, 15, 4, 0), true},
+		{"2017-07-05 15:04:05", at(2017, 7, 5, 15, 4, 5), true},
+		{"2017/7/5", at(2017, 7, 5, 0, 0, 0), true},
+		{"2017.07.05", at(2017, 7, 5, 0, 0, 0), true},
+		{"20170705", at(2017, 7, 5, 0, 0, 0), true},
+		{"2017-07-05T15:04:05Z", time.Date(2017, 7, 5, 15, 4, 5, 0, time.UTC), true},
+		{"2017-07-05T23:04:05+08:00", at(2017, 7, 5, 23, 4, 5), true},
+		{"今天", at(2017, 7, 5, 0, 0, 0), true},
+		{"昨天 08:30", at(2017, 7, 4, 8, 30, 0), true},
+		{"前天", at(2017, 7, 3, 0, 0, 0), true},
+		{"刚刚", now, true},
+		{"5分钟前", now.Add(-5 * time.Minute), true},
+		{"3小时前", now.Add(-3 * time.Hour), true},
+		{"2天前", at(2017, 7, 3, 10, 30, 0), true},
+		{"2017年02月30日", time.Time{}, false},
+		{"2017-13-01", time.Time{}, false},
+		{"2017-07-05 24:00", time.Time{}, false},
+		{"17-07-05", time.Time{}, false},
+		{"", time.Time{}, false},
+		{"暂无", time.Time{}, false},
+	}
+	for _, c := range cases {
+		got, ok := DateAt(c.in, now)
+		if ok != c.ok || ok && !got.Equal(c.want) {
+			t.Errorf("DateAt(%q) = %v, %v; want %v, %v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+
+	// 日期按北京时间解析，而非 UTC 或本地时区
+	if got, _ := Date("2017年07月05日"); got.Unix() != 1499184000 {
+		t.Errorf("2017年07月05日 = %v, want 2017-07-05 00:00 +0800", got)
+	}
+}
+
+func TestScore(t *testing.T) {
+	cases := []struct {
+		value float64
+		scale string
+		want  float64
+	}{
+		{55, ScalePercent, 0.55},
+		{100, ScalePercent, 1},
+		{120, ScalePercent, 1},
+		{1, ScaleStars, 0},
+		{3, ScaleStars, 0.5},
+		{4.2, ScaleStars, 0.8},
+		{5, ScaleStars, 1},
+		{0.5, ScaleStars, 0},
+		{0, ScaleStars, 0},
+		{4, "", 0},
+	}
+	for _, c := range cases {
+		if got := Score(c.value, c.scale); got < c.want-1e-9 || got > c.want+1e-9 {
+			t.Errorf("Score(%v, %q) = %v, want %v", c.value, c.scale, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package norm
+
+import (
+	"math"
+	"strings"
+)
+
+// 应用市场页面上的数字写法各异：`1.28亿`、`5万+`、`21.5亿次`、`1,234`、`３千`，偶尔为纯中文数字`一万二千`
+// 本包将数字、大小、百分比、星级评分与日期统一解析为整数与带时区的时间，无法解析时返回 false 而不会 panic
+
+// width 将全角数字与符号转为半角，并去除数字中的千分位逗号
+var width = strings.NewReplacer(
+	"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+	"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+	"．", ".", "％", "%", "＋", "+", "／", "/", "：", ":",
+	",", "", "，", "",
+)
+
+// decorations 为数字后可忽略的修饰，如 `5万+`、`21.5亿次`、`10万余人`
+var decorations = []string{"+", "次", "人", "余", "多"}
+
+// units 为数字后的量词及其对应的 10 的幂，较长的在前
+var units = []struct {
+	suffix string
+	exp    int
+}{
+	{"万亿", 12}, {"千万", 7}, {"百万", 6}, {"亿", 8}, {"万", 4}, {"千", 3}, {"百", 2},
+	{"w", 4}, {"W", 4}, {"k", 3}, {"K", 3},
+}
+
+// Number 解析带中文量词的数字，如 `1.28亿` => 128000000、`5万+` => 50000、`1,234次` => 1234、`一万二千` => 12000
+// 小数部分超出精度时截断，负数、溢出与无法识别的写法返回 false
+func Number(s string) (int64, bool) {
+	s = strings.TrimSpace(width.Replace(s))
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, d := range decorations {
+			if strings.HasSuffix(s, d) {
+				s, trimmed = strings.TrimSpace(strings.TrimSuffix(s, d)), true
+			}
+		}
+	}
+	if isChinese(s) {
+		return chinese(s)
+	}
+	exp := 0
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s, exp = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.exp
+			break
+		}
+	}
+	mantissa, frac, ok := decimal(s)
+	if !ok {
+		return 0, false
+	}
+	return shift(mantissa, exp-frac)
+}
+
+// decimal 将 `12.34` 解析为尾数 1234 与小数位数 2，至少须有一位数字，最多一个小数点
+func decimal(s string) (mantissa int64, frac int, ok bool) {
+	digits, dot := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case '0' <= c && c <= '9':
+			if mantissa > (math.MaxInt64-int64(c-'0'))/10 {
+				return 0, 0, false
+			}
+			mantissa = mantissa*10 + int64(c-'0')
+			digits++
+			if dot {
+				frac++
+			}
+		case c == '.' && !dot:
+			dot = true
+		default:
+			return 0, 0, false
+		}
+	}
+	return mantissa, frac, digits > 0
+}
+
+// shift 返回 v * 10^exp，exp 为负时截断，溢出时返回 false
+func shift(v int64, exp int) (int64, bool) {
+	for ; exp < 0 && v != 0; exp++ {
+		v /= 10
+	}
+	for ; exp > 0 && v != 0; exp-- {
+		if v > math.MaxInt64/10 {
+			return 0, false
+		}
+		v *= 10
+	}
+	return v, true
+}
+
+// mul 返回 a * b，溢出时返回 false，a 与 b 均非负
+func mul(a, b int64) (int64, bool) {
+	if a != 0 && b > math.MaxInt64/a {
+		return 0, false
+	}
+	return a * b, true
+}
+
+// add 返回 a + b，溢出时返回 false，a 与 b 均非负
+func add(a, b int64) (int64, bool) {
+	if a > math.MaxInt64-b {
+		return 0, false
+	}
+	return a + b, true
+}
+
+var zhDigits = map[rune]int64{
+	'零': 0, '〇': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4,
+	'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+var zhUnits = map[rune]int64{'十': 10, '百': 100, '千': 1000, '万': 1e4, '亿': 1e8}
+
+// isChinese 判断字符串是否全部由中文数字与量词组成
+func isChinese(s string) bool {
+	for _, r := range s {
+		if _, ok := zhDigits[r]; ok {
+			continue
+		}
+		if _, ok := zhUnits[r]; !ok {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// chinese 解析中文数字，如 `一亿二千万`、`十二`、`一百零五`
+// total 为已完成的万、亿节，section 为当前节内十、百、千的累计，digit 为尚未乘以单位的数字
+func chinese(s string) (int64, bool) {
+	var total, section, digit int64
+	var ok, seen bool
+	for _, r := range s {
+		if d, isDigit := zhDigits[r]; isDigit {
+			digit, seen = d, true
+			continue
+		}
+		switch unit := zhUnits[r]; r {
+		case '十', '百', '千':
+			if digit == 0 && r == '十' {
+				digit = 1 // `十二` 即 `一十二`
+			}
+			section += digit * unit
+			digit, seen = 0, true
+		case '万':
+			if total, ok = add(total, (section+digit)*unit); !ok {
+				return 0, false
+			}
+			section, digit = 0, 0
+		case '亿':
+			if total, ok = add(total, section+digit); !ok {
+				return 0, false
+			}
+			if total, ok = mul(total, unit); !ok {
+				return 0, false
+			}
+			section, digit = 0, 0
+		}
+	}
+	if !seen {
+		return 0, false
+	}
+	return add(total, section+digit)
+}
@@ -0,0 +1,79 @@
+package norm
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Percent 解析百分比为 0 到 100 的整数，如 `97.00%` => 97、`好评率 85.5%` => 85，小数部分截断
+func Percent(s string) (int64, bool) {
+	s = strings.TrimSpace(width.Replace(s))
+	s = strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	s = strings.TrimLeftFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	f, ok := float(s)
+	if !ok || f > 100 {
+		return 0, false
+	}
+	return floor(f), true
+}
+
+// Stars 将星级评分转为百分制，如满分为 5 时 `4.3` 与 `4.3分` => 86，`4/5` 中的满分优先于 best
+// best 不大于 0 时按 5 分制，评分超出满分时返回 false
+func Stars(s string, best float64) (int64, bool) {
+	s = strings.TrimSpace(width.Replace(s))
+	for _, suffix := range []string{"分", "星"} {
+		s = strings.TrimSpace(strings.TrimSuffix(s, suffix))
+	}
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		b, ok := float(strings.TrimSpace(s[i+1:]))
+		if !ok || b == 0 {
+			return 0, false
+		}
+		s, best = strings.TrimSpace(s[:i]), b
+	}
+	if best <= 0 {
+		best = 5
+	}
+	f, ok := float(s)
+	if !ok || f > best {
+		return 0, false
+	}
+	return floor(f * 100 / best), true
+}
+
+// float 解析非负的十进制小数，拒绝 `NaN`、`Inf` 与指数写法
+func float(s string) (float64, bool) {
+	if strings.Trim(s, "0123456789.") != "" || strings.Count(s, ".") > 1 || strings.Trim(s, ".") == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// floor 截断为整数，容忍二进制浮点误差，如 4.1*100/5 = 81.99999999999999 => 82
+func floor(f float64) int64 {
+	return int64(math.Floor(f + 1e-9))
+}
+
+// 各来源评分的量纲，与原始评分一起保存，便于跨来源比较
+const (
+	ScalePercent = "percent" // 好评率，0~100，如豌豆荚
+	ScaleStars   = "stars"   // 五星平均分，1~5，如应用宝
+)
+
+// Score 将原始评分按量纲归一化到 [0,1]：好评率除以 100，五星平均分按最低 1 星、最高 5 星线性换算，
+// 即 1 星为 0、3 星为 0.5、5 星为 1。未知量纲或评分为 0（无评分）时返回 0
+func Score(value float64, scale string) float64 {
+	var score float64
+	switch scale {
+	case ScalePercent:
+		score = value / 100
+	case ScaleStars:
+		if value <= 0 {
+			return 0
+		}
+		score = (value - 1) / 4
+	}
+	return math.Max(0, math.Min(1, score))
+}
@@ -0,0 +1,36 @@
+package norm
+
+import (
+	"math"
+	"strings"
+)
+
+// sizeUnits 为大小单位对应的二进制位移，不区分大小写，`MB` 与 `MiB` 同为 1<<20
+var sizeUnits = map[string]uint{
+	"": 0, "B": 0, "BYTES": 0, "字节": 0,
+	"K": 10, "KB": 10, "KIB": 10,
+	"M": 20, "MB": 20, "MIB": 20, "兆": 20,
+	"G": 30, "GB": 30, "GIB": 30,
+	"T": 40, "TB": 40, "TIB": 40,
+	"P": 50, "PB": 50, "PIB": 50,
+	"E": 60, "EB": 60, "EIB": 60,
+}
+
+// Size 解析文件大小为字节数，如 `128k` => 131072、`25 MB` => 26214400、`43.08MB`、`1,024KB`
+// 单位按 1024 进位，小数部分截断，溢出与未知单位返回 false
+func Size(s string) (int64, bool) {
+	s = strings.TrimSpace(width.Replace(s))
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	bits, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, false
+	}
+	mantissa, frac, ok := decimal(s[:i])
+	if !ok || mantissa > math.MaxInt64>>bits {
+		return 0, false
+	}
+	return shift(mantissa<<bits, -frac)
+}
@@ -0,0 +1,83 @@
+package perm
+
+import (
+	"time"
+	"strings"
+)
+
+import (
+	"github.com/go-pg/pg/orm"
+)
+
+// 权限变化的类型
+const (
+	Added   = "added"   // 新增权限
+	Removed = "removed" // 移除权限
+)
+
+// Change 是应用在一次抓取中新增或移除的一个权限，对应 perm_change 表
+type Change struct {
+	Source       string    `json:"source" sql:",pk"`        // 来源 wdj/sjqq
+	AppID        string    `json:"app_id" sql:",pk"`        // 应用包名
+	Permission   string    `json:"permission" sql:",pk"`    // 权限名，无法映射时为描述原文
+	Action       string    `json:"action" sql:",pk"`        // Added 或 Removed
+	Level        string    `json:"level"`                   // 保护级别，无法映射时为空
+	Description  string    `json:"description"`             // 来源展示的描述
+	DetectedTime time.Time `json:"detected_time" sql:",pk"` // 发现变化的时间
+	tableName    struct{}  `sql:"perm_change"`
+}
+
+// entry 是解析后的一个权限，key 为权限名，无法映射时为描述原文
+type entry struct {
+	key, level, desc string
+}
+
+// entries 按首次出现的顺序解析描述列表并去重
+func entries(descs []string) (list []entry, index map[string]bool) {
+	index = make(map[string]bool, len(descs))
+	for _, desc := range descs {
+		e := entry{key: strings.TrimSpace(desc), desc: strings.TrimSpace(desc)}
+		if p, ok := Lookup(desc); ok {
+			e.key, e.level = p.Name, p.Level
+		}
+		if e.key == "" || index[e.key] {
+			continue
+		}
+		index[e.key] = true
+		list = append(list, e)
+	}
+	return
+}
+
+// Diff 比较应用前后两次抓取的权限描述，返回新增与移除的权限，新增的在前。
+// 两侧均按当前映射表解析，措辞变化或映射表更新不会产生变化
+func Diff(source, appID string, old, new []string, at time.Time) (changes []Change) {
+	oldList, oldIndex := entries(old)
+	newList, newIndex := entries(new)
+	add := func(e entry, action string) {
+		changes = append(changes, Change{
+			Source: source, AppID: appID, Permission: e.key, Action: action,
+			Level: e.level, Description: e.desc, DetectedTime: at,
+		})
+	}
+	for _, e := range newList {
+		if !oldIndex[e.key] {
+			add(e, Added)
+		}
+	}
+	for _, e := range oldList {
+		if !newIndex[e.key] {
+			add(e, Removed)
+		}
+	}
+	return
+}
+
+// SaveChanges 将权限变化写入 perm_change 表，重复的记录会被忽略，db 可为事务
+func SaveChanges(db orm.DB, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	_, err := db.Model(&changes).OnConflict("DO NOTHING").Insert()
+	return err
+}
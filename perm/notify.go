@@ -0,0 +1,244 @@
+package perm
+
+import (
+	"os"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"bytes"
+	"errors"
+	"strings"
+	"net/http"
+	"encoding/json"
+)
+
+// Notifier 发出权限变化告警，实现须可并发使用
+type Notifier interface {
+	Notify(changes []Change) error
+}
+
+// Filter 筛选需要告警的权限变化，各条件为空时不限制
+type Filter struct {
+	Levels       []string // 保护级别，如 Dangerous，只限制能映射的权限
+	Permissions  []string // 权限名或描述，描述按映射表转为权限名
+	Apps         []string // 应用包名
+	Removed      bool     // 是否告警移除的权限，默认仅告警新增
+	SkipUnmapped bool     // 不告警无法映射的权限；默认不论 Levels 均告警，未收录的新权限可能同样危险
+}
+
+// Match 判断权限变化是否需要告警
+func (f *Filter) Match(c Change) bool {
+	if c.Action == Removed && !f.Removed {
+		return false
+	}
+	if c.Level == "" && f.SkipUnmapped {
+		return false
+	}
+	if c.Level != "" && len(f.Levels) > 0 && !contains(f.Levels, c.Level) {
+		return false
+	}
+	if len(f.Apps) > 0 && !contains(f.Apps, c.AppID) {
+		return false
+	}
+	if len(f.Permissions) == 0 {
+		return true
+	}
+	for _, want := range f.Permissions {
+		if p, ok := Lookup(want); (ok && p.Name == c.Permission) || strings.TrimSpace(want) == c.Permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply 返回需要告警的权限变化
+func (f *Filter) Apply(changes []Change) (matched []Change) {
+	for _, c := range changes {
+		if f.Match(c) {
+			matched = append(matched, c)
+		}
+	}
+	return
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifiers 依次调用每个 Notifier，某个失败不影响其余，返回遇到的第一个错误
+type Notifiers []Notifier
+
+// Notify 实现 Notifier
+func (ns Notifiers) Notify(changes []Change) (err error) {
+	for _, n := range ns {
+		if e := n.Notify(changes); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// String 格式化为一行文本，用于日志
+func (c Change) String() string {
+	sign, level := "+", c.Level
+	if c.Action == Removed {
+		sign = "-"
+	}
+	if level == "" {
+		level = "unknown"
+	}
+	return fmt.Sprintf("%s %s %s%s (%s, %s)", c.Source, c.AppID, sign, c.Permission, level, c.Description)
+}
+
+// ErrQueueFull 表示 AsyncNotifier 的队列已满或已关闭，变化被丢弃
+var ErrQueueFull = errors.New("perm: notify queue is full or closed")
+
+// AsyncNotifier 在后台协程中依次调用 Notifier，Notify 只入队而不等待发出，
+// 缓慢或失败的 webhook 不会阻塞调用方；队列已满或已关闭时丢弃变化并返回 ErrQueueFull
+type AsyncNotifier struct {
+	notifier Notifier
+	onError  func(changes []Change, err error)
+	queue    chan []Change
+	done     chan struct{}
+	mu       sync.RWMutex
+	closed   bool
+}
+
+// NewAsyncNotifier 返回队列长度为 size 的 AsyncNotifier，n 返回错误时调用 onError，onError 可为空
+func NewAsyncNotifier(n Notifier, size int, onError func(changes []Change, err error)) *AsyncNotifier {
+	a := &AsyncNotifier{notifier: n, onError: onError, queue: make(chan []Change, size), done: make(chan struct{})}
+	go a.run()
+	return a
+}
+
+func (a *AsyncNotifier) run() {
+	defer close(a.done)
+	for changes := range a.queue {
+		if err := a.notifier.Notify(changes); err != nil && a.onError != nil {
+			a.onError(changes, err)
+		}
+	}
+}
+
+// Notify 实现 Notifier
+func (a *AsyncNotifier) Notify(changes []Change) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return ErrQueueFull
+	}
+	select {
+	case a.queue <- changes:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close 不再接受新的变化，等待队列中的变化发出后返回，可重复调用
+func (a *AsyncNotifier) Close() {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+	<-a.done
+}
+
+// LogNotifier 将每个变化输出为一行日志
+type LogNotifier struct {
+	Printf func(format string, args ...interface{}) // 日志函数，为空时使用标准库 log.Printf
+}
+
+// Notify 实现 Notifier
+func (n LogNotifier) Notify(changes []Change) error {
+	printf := n.Printf
+	if printf == nil {
+		printf = log.Printf
+	}
+	for _, c := range changes {
+		printf("[PERM] %s", c)
+	}
+	return nil
+}
+
+// WebhookNotifier 将变化以 JSON `{"changes":[...]}` POST 到 URL，响应非 2xx 视为失败
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client // 为空时使用10秒超时的客户端
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// Notify 实现 Notifier
+func (n *WebhookNotifier) Notify(changes []Change) error {
+	body, err := json.Marshal(struct {
+		Changes []Change `json:"changes"`
+	}{changes})
+	if err != nil {
+		return err
+	}
+	client := n.Client
+	if client == nil {
+		client = webhookClient
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// FileNotifier 将每个变化以 JSON 行追加到文件
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Notify 实现 Notifier
+func (n *FileNotifier) Notify(changes []Change) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, c := range changes {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ParseNotifier 按名称构造 Notifier：`log`、http(s) 地址或 `file:<path>`
+func ParseNotifier(s string) (Notifier, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "log":
+		return LogNotifier{}, nil
+	case strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://"):
+		return &WebhookNotifier{URL: s}, nil
+	case strings.HasPrefix(s, "file:") && len(s) > len("file:"):
+		return &FileNotifier{Path: strings.TrimPrefix(s, "file:")}, nil
+	}
+	return nil, fmt.Errorf("invalid notifier %q, use log, http(s)://... or file:<path>", s)
+}
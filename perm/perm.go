@@ -0,0 +1,124 @@
+package perm
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 应用市场展示的权限为中文说明，如豌豆荚的 `读取短信或彩信` 与应用宝的 `允许应用程序读取短信`，
+// 同一权限在各来源的写法不同，也无法与 AndroidManifest 中的声明比较。本包将其映射为 `android.permission.*`
+
+// 保护级别，见 https://developer.android.com/guide/topics/permissions/overview
+const (
+	Normal    = "normal"    // 安装时自动授予
+	Dangerous = "dangerous" // 涉及隐私，运行时须用户授权
+	Signature = "signature" // 仅授予同签名或系统应用
+	AppOp     = "appop"     // signature|appop，普通应用须用户在设置中单独开启，如悬浮窗、安装应用
+)
+
+// Permission 为一个 Android 权限
+type Permission struct {
+	Name  string // 完整的权限名，如 `android.permission.READ_CONTACTS`
+	Level string // 保护级别 Normal、Dangerous、Signature 或 AppOp
+}
+
+const prefix = "android.permission."
+
+var (
+	byName   = make(map[string]*Permission) // 完整权限名 => 权限
+	byDesc   = make(map[string]*Permission) // 规范化的描述 => 权限
+	prefixes []string                       // 按前缀匹配的规范化描述，较长的在前
+	byPrefix = make(map[string]*Permission)
+)
+
+func init() {
+	for _, line := range strings.Split(table, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		p := &Permission{Name: fields[0], Level: fields[1]}
+		if !strings.Contains(p.Name, ".") {
+			p.Name = prefix + p.Name
+		}
+		byName[p.Name] = p
+		for _, desc := range strings.Split(strings.Join(fields[2:], " "), "|") {
+			if strings.HasSuffix(desc, "*") {
+				k := key(strings.TrimSuffix(desc, "*"))
+				prefixes = append(prefixes, k)
+				byPrefix[k] = p
+			} else {
+				byDesc[key(desc)] = p
+			}
+		}
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+}
+
+// key 规范化描述：全角转半角，转为小写，去除空白、标点与符号
+func key(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Get 按权限名查找，可省略 `android.permission.` 前缀，如 `CAMERA`
+func Get(name string) (*Permission, bool) {
+	name = strings.TrimSpace(name)
+	if p, ok := byName[name]; ok {
+		return p, true
+	}
+	p, ok := byName[prefix+name]
+	return p, ok
+}
+
+// Lookup 将应用市场的中文描述映射为权限，描述本身为权限名时也可识别
+func Lookup(desc string) (*Permission, bool) {
+	if p, ok := Get(desc); ok {
+		return p, true
+	}
+	k := key(desc)
+	if k == "" {
+		return nil, false
+	}
+	if p, ok := byDesc[k]; ok {
+		return p, true
+	}
+	for _, pre := range prefixes {
+		if strings.HasPrefix(k, pre) {
+			return byPrefix[pre], true
+		}
+	}
+	return nil, false
+}
+
+// Resolve 将描述列表映射为权限名列表，按首次出现的顺序去重，
+// 无法映射的描述原样保留在 unmapped 中，供人工核对后增补映射表
+func Resolve(descs []string) (names, unmapped []string) {
+	seen := make(map[string]bool, len(descs))
+	for _, desc := range descs {
+		desc = strings.TrimSpace(desc)
+		if desc == "" || seen[desc] {
+			continue
+		}
+		p, ok := Lookup(desc)
+		switch {
+		case !ok:
+			seen[desc] = true
+			unmapped = append(unmapped, desc)
+		case !seen[p.Name]:
+			seen[p.Name] = true
+			names = append(names, p.Name)
+		}
+	}
+	return
+}
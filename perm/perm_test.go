@@ -0,0 +1,312 @@
+package perm
+
+import (
+	"os"
+	"sync"
+	"time"
+	"regexp"
+	"strings"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"encoding/json"
+	"path/filepath"
+	"net/http/httptest"
+)
+
+func TestLookup(t *testing.T) {
+	cases := []struct{ desc, want string }{
+		{"读取短信或彩信", "android.permission.READ_SMS"},
+		{"允许应用程序读取短信", "android.permission.READ_SMS"},
+		{"访问联系人", "android.permission.READ_CONTACTS"},
+		{"允许应用程序读取用户联系人数据", "android.permission.READ_CONTACTS"},
+		{" 允许应用程序访问Wi-Fi网络的信息 ", "android.permission.ACCESS_WIFI_STATE"},
+		{"允许应用程序访问wifi网络的信息", "android.permission.ACCESS_WIFI_STATE"},
+		{"允许应用程序写入（但不读取）用户的通话记录数据", "android.permission.WRITE_CALL_LOG"},
+		{"允许应用程序调用  killBackgroundProcesses(String)", "android.permission.KILL_BACKGROUND_PROCESSES"},
+		{"允许应用程序接收 ACTION_BOOT_COMPLETED系统启动完成后广播如果不要求此权限", "android.permission.RECEIVE_BOOT_COMPLETED"},
+		{"允许安装在发射器的快捷方式的应用程序", "com.android.launcher.permission.INSTALL_SHORTCUT"},
+		{"android.permission.CAMERA", "android.permission.CAMERA"},
+		{"CAMERA", "android.permission.CAMERA"},
+	}
+	for _, c := range cases {
+		if p, ok := Lookup(c.desc); !ok || p.Name != c.want {
+			t.Errorf("Lookup(%q) = %+v, %v; want %s", c.desc, p, ok, c.want)
+		}
+	}
+	for _, desc := range []string{"", "  ", "读取", "允许", "允许从传感器读取位置", "android.permission.UNKNOWN"} {
+		if p, ok := Lookup(desc); ok {
+			t.Errorf("Lookup(%q) = %+v, want not found", desc, p)
+		}
+	}
+	if p, ok := Get("SEND_SMS"); !ok || p.Level != Dangerous {
+		t.Errorf("Get(SEND_SMS) = %+v, %v", p, ok)
+	}
+	if p, ok := Get("android.permission.INTERNET"); !ok || p.Level != Normal {
+		t.Errorf("Get(INTERNET) = %+v, %v", p, ok)
+	}
+	if p, ok := Get("SYSTEM_ALERT_WINDOW"); !ok || p.Level != AppOp {
+		t.Errorf("Get(SYSTEM_ALERT_WINDOW) = %+v, %v", p, ok)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	names, unmapped := Resolve([]string{"读取短信或彩信", "允许应用程序读取短信", "", "某个新权限", "拍照", "某个新权限"})
+	if strings.Join(names, ",") != "android.permission.READ_SMS,android.permission.CAMERA" {
+		t.Errorf("names = %v", names)
+	}
+	if len(unmapped) != 1 || unmapped[0] != "某个新权限" {
+		t.Errorf("unmapped = %v", unmapped)
+	}
+	if names, unmapped = Resolve(nil); names != nil || unmapped != nil {
+		t.Errorf("Resolve(nil) = %v, %v", names, unmapped)
+	}
+}
+
+// apiName 匹配描述中引用的 API 名称，如 ACTION_BOOT_COMPLETED、killBackgroundProcesses
+var apiName = regexp.MustCompile(`[A-Za-z_]{8,}`)
+
+// TestTable 检查映射表：保护级别有效，同一描述不映射到不同权限，前缀不与完整描述冲突且引用了 API 名称
+func TestTable(t *testing.T) {
+	descs := make(map[string]string)
+	for _, line := range strings.Split(table, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 3 {
+			t.Errorf("malformed line: %q", line)
+			continue
+		}
+		if level := fields[1]; level != Normal && level != Dangerous && level != Signature && level != AppOp {
+			t.Errorf("%s: invalid level %s", fields[0], level)
+		}
+		for _, desc := range strings.Split(strings.Join(fields[2:], " "), "|") {
+			if strings.HasSuffix(desc, "*") && !apiName.MatchString(desc) {
+				t.Errorf("%s: prefix %q should quote an api name, or list the full description", fields[0], desc)
+			}
+			k := key(strings.TrimSuffix(desc, "*"))
+			if k == "" {
+				t.Errorf("%s: empty description", fields[0])
+			}
+			if other, ok := descs[k]; ok && other != fields[0] {
+				t.Errorf("%q maps to both %s and %s", desc, other, fields[0])
+			}
+			descs[k] = fields[0]
+		}
+	}
+	for k, name := range descs {
+		if p, ok := Lookup(k); !ok || !strings.HasSuffix(p.Name, name) {
+			t.Errorf("%q of %s resolves to %+v", k, name, p)
+		}
+	}
+}
+
+// TestKnownDescriptions 检查各来源样例页面中出现过的描述均已收录，未收录的描述不会产生告警级别
+func TestKnownDescriptions(t *testing.T) {
+	for _, desc := range []string{
+		// 豌豆荚 wdj/sample
+		"读取短信或彩信",
+		"访问联系人",
+		"发送短信或彩信",
+		// 应用宝 sjqq/sample
+		"允许应用程序进入的Wi-Fi多播模式",
+		"允许程序访问有关网络的信息",
+		"允许应用程序访问的大致位置",
+		"允许应用访问精确位置",
+		"需要能够访问摄像机装置",
+		"允许应用程序打开网络套接字",
+		"允许应用程序修改全局音频设置",
+		"允许应用程序接收 ACTION_BOOT_COMPLETED系统启动完成后广播如果不要求此权限，您将不会在那个时候接收到广播虽然持有此权限没有任何安全问题，它可以通过增加花费的时间系统启动量，允许应用程序对用户体验造成负面影响有自己运行在用户不知道他们因此，必须明确声明你的这个设施的使用，使用户能看得到",
+		"允许应用程序录制音频",
+		"允许应用程序读取用户联系人数据",
+		"允许应用程序读取短信",
+		"允许访问振动",
+		"允许使用PowerManager WakeLocks让处理器进入休眠或屏幕变暗",
+		"允许应用程序写入到外部存储器",
+		"允许应用程序写入用户的联系人数据",
+		"允许应用程序读取或写入系统设置",
+		"允许安装在发射器的快捷方式的应用程序",
+		"允许应用程序卸载启动的快捷方式",
+		"允许应用程序连接到已配对的蓝牙设备",
+		"允许应用程序发现和配对蓝牙设备",
+		"允许应用程序广播常用意图这些广播数据由该系统被完成之后保持，以便客户端可以快速地检索数据，而不必等待下一个广播",
+		"允许应用程序创建一个使用类型的窗口 TYPE_SYSTEM_ALERT，所有其他应用程序的顶部只有极少数的应用程序应该使用此权限; 这些窗口用于与用户的系统级相互作用",
+		"允许应用程序更改Wi-Fi连接状态",
+		"允许找出任何package占用空间的应用程序",
+		"允许应用程序通过NFC进行I/O操作",
+		"允许应用程序从外部存储读取",
+		"允许应用程序访问Wi-Fi网络的信息",
+		"允许应用程序广播一个Intent设置为用户报警",
+		"允许从传感器，用户使用来衡量什么是他/她的身体内部发生的情况，如心脏速率访问数据的应用程序",
+		"允许访问的帐户服务帐户列表",
+		"允许应用程序使用指纹硬件",
+		"允许应用程序读取同步设置",
+		"允许程序写入同步设置",
+		"允许只读到电话状态访问，包括该装置的电话号码，当前蜂窝网络信息，任何正在进行的呼叫的状态，并且任何一个列表 PhoneAccount的注册在设备上",
+		"允许应用程序修改当前设置，如本地化",
+		"允许应用程序调用  killBackgroundProcesses(String)",
+		"允许一个程序初始化一个电话拨号不需通过拨号用户界面去为用户确认呼叫",
+		"允许应用程序使其活动持续",
+		"允许应用程序发送短信",
+		"允许应用程序读取低级别的系统日志文件",
+		"允许应用程序改变网络连接状态",
+		"允许应用程序展开或折叠状态栏",
+		"允许应用程序读取用户的日历数据",
+		"允许应用程序写入用户的日历数据",
+		"允许应用程序读取用户的通话记录",
+		"允许应用程序禁用键盘锁，如果它是不安全的",
+		"允许应用程序写入（但不读取）用户的通话记录数据",
+		"允许安装和可移动存储卸载文件系统",
+	} {
+		if _, ok := Lookup(desc); !ok {
+			t.Errorf("%q is not mapped", desc)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	at := time.Date(2017, 8, 1, 12, 0, 0, 0, time.UTC)
+	old := []string{"访问网络", "读取联系人", "某个旧权限"}
+	// 措辞变化与重复描述不是变化
+	changes := Diff("wdj", "com.tencent.mm", old, []string{"允许应用程序打开网络套接字", "读取短信或彩信", "允许应用程序读取短信", "某个新权限"}, at)
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Action+" "+c.Permission+" "+c.Level)
+		if c.Source != "wdj" || c.AppID != "com.tencent.mm" || !c.DetectedTime.Equal(at) || c.Description == "" {
+			t.Errorf("change = %+v", c)
+		}
+	}
+	want := []string{
+		"added android.permission.READ_SMS dangerous",
+		"added 某个新权限 ",
+		"removed android.permission.READ_CONTACTS dangerous",
+		"removed 某个旧权限 ",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Diff = %q, want %q", got, want)
+	}
+	if changes = Diff("wdj", "a", old, old, at); len(changes) != 0 {
+		t.Errorf("Diff of same permissions = %v", changes)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	sms := Change{AppID: "a", Permission: "android.permission.READ_SMS", Action: Added, Level: Dangerous}
+	net := Change{AppID: "b", Permission: "android.permission.INTERNET", Action: Added, Level: Normal}
+	gone := Change{AppID: "a", Permission: "android.permission.CAMERA", Action: Removed, Level: Dangerous}
+	unknown := Change{AppID: "a", Permission: "某个新权限", Action: Added}
+	all := []Change{sms, net, gone, unknown}
+	cases := []struct {
+		filter Filter
+		want   int
+	}{
+		{Filter{}, 3},
+		{Filter{Removed: true}, 4},
+		{Filter{Levels: []string{Dangerous}}, 2},
+		{Filter{Levels: []string{Dangerous}, Removed: true}, 3},
+		{Filter{Levels: []string{Dangerous}, SkipUnmapped: true}, 1},
+		{Filter{Apps: []string{"b"}}, 1},
+		{Filter{Permissions: []string{"读取短信", "某个新权限"}}, 2},
+		{Filter{Permissions: []string{"CAMERA"}}, 0},
+	}
+	for _, c := range cases {
+		if got := c.filter.Apply(all); len(got) != c.want {
+			t.Errorf("%+v matched %v, want %d", c.filter, got, c.want)
+		}
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	changes := []Change{{Source: "sjqq", AppID: "a", Permission: "android.permission.READ_SMS", Action: Added, Level: Dangerous}}
+
+	var received []Change
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Changes []Change }
+		json.NewDecoder(r.Body).Decode(&body)
+		received = body.Changes
+	}))
+	defer server.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	dir, err := ioutil.TempDir("", "perm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "perm.jsonl")
+
+	var logged []string
+	var ns Notifiers
+	for _, s := range []string{"log", server.URL, "file:" + file} {
+		n, err := ParseNotifier(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ns = append(ns, n)
+	}
+	ns[0] = LogNotifier{Printf: func(format string, args ...interface{}) { logged = append(logged, args[0].(Change).String()) }}
+	for i := 0; i < 2; i++ {
+		if err = ns.Notify(changes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(logged) != 2 || logged[0] != "sjqq a +android.permission.READ_SMS (dangerous, )" {
+		t.Errorf("logged = %q", logged)
+	}
+	if len(received) != 1 || received[0].Permission != changes[0].Permission {
+		t.Errorf("webhook received %+v", received)
+	}
+	if data, _ := ioutil.ReadFile(file); strings.Count(string(data), "\n") != 2 || !strings.Contains(string(data), `"level":"dangerous"`) {
+		t.Errorf("file = %s", data)
+	}
+	if err = (&WebhookNotifier{URL: failing.URL}).Notify(changes); err == nil {
+		t.Error("non 2xx webhook response should fail")
+	}
+	for _, s := range []string{"", "mail", "file:", "ftp://a"} {
+		if _, err = ParseNotifier(s); err == nil {
+			t.Errorf("ParseNotifier(%q) should fail", s)
+		}
+	}
+}
+
+func TestAsyncNotifier(t *testing.T) {
+	changes := []Change{{Source: "sjqq", AppID: "a", Permission: "android.permission.READ_SMS", Action: Added, Level: Dangerous}}
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var sent, failed int
+	slow := LogNotifier{Printf: func(format string, args ...interface{}) {
+		<-release
+		mu.Lock()
+		sent++
+		mu.Unlock()
+	}}
+	a := NewAsyncNotifier(Notifiers{slow, &WebhookNotifier{URL: "ftp://a"}}, 1, func(changes []Change, err error) {
+		mu.Lock()
+		failed++
+		mu.Unlock()
+	})
+
+	// 第一批被后台协程取出并阻塞，第二批进入队列，第三批因队列已满被丢弃，调用方均不阻塞
+	if err := a.Notify(changes); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for a.Notify(changes) != nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := a.Notify(changes); err != ErrQueueFull {
+		t.Errorf("notify with full queue: %v", err)
+	}
+	close(release)
+	a.Close()
+	if err := a.Notify(changes); err != ErrQueueFull {
+		t.Errorf("notify after close: %v", err)
+	}
+	if sent != 2 || failed != 2 {
+		t.Errorf("sent %d, failed %d, want 2 and 2", sent, failed)
+	}
+}
@@ -0,0 +1,64 @@
+package perm
+
+// 内置映射表，每行为 `权限名 保护级别 描述|描述|...`，权限名不含点时补全为 `android.permission.*`
+// 描述为各应用市场展示的中文说明，比较时忽略空白、标点与大小写；以 `*` 结尾的描述按前缀匹配，
+// 仅用于应用宝引用了 API 名称的长描述，前缀须包含该名称，其余描述一律完整收录。发现未收录的描述时（见 `android perms unmapped`）直接在此增补
+const table = `
+READ_CONTACTS           dangerous  读取联系人|访问联系人|读取联系人数据|允许应用程序读取用户联系人数据
+WRITE_CONTACTS          dangerous  修改联系人|写入联系人|允许应用程序写入用户的联系人数据
+GET_ACCOUNTS            dangerous  访问账户列表|获取账户|查找设备上的账户|允许访问的帐户服务帐户列表
+READ_CALENDAR           dangerous  读取日历|读取日历活动|允许应用程序读取用户的日历数据
+WRITE_CALENDAR          dangerous  修改日历|添加或修改日历活动|允许应用程序写入用户的日历数据
+READ_SMS                dangerous  读取短信或彩信|读取短信|允许应用程序读取短信
+SEND_SMS                dangerous  发送短信或彩信|发送短信|允许应用程序发送短信
+RECEIVE_SMS             dangerous  接收短信|允许应用程序接收短信
+RECEIVE_MMS             dangerous  接收彩信|允许应用程序监控传入的彩信
+CALL_PHONE              dangerous  拨打电话|直接拨打电话号码|允许一个程序初始化一个电话拨号不需通过拨号用户界面去为用户确认呼叫
+READ_PHONE_STATE        dangerous  读取电话状态|读取手机状态和身份|获取手机信息|读取手机识别码|允许只读到电话状态访问，包括该装置的电话号码，当前蜂窝网络信息，任何正在进行的呼叫的状态，并且任何一个列表 PhoneAccount的注册在设备上
+READ_CALL_LOG           dangerous  读取通话记录|允许应用程序读取用户的通话记录
+WRITE_CALL_LOG          dangerous  修改通话记录|写入通话记录|允许应用程序写入但不读取用户的通话记录数据
+PROCESS_OUTGOING_CALLS  dangerous  监控拨出电话|重新设置外拨电话的路径|允许应用程序查看拨出电话的号码
+CAMERA                  dangerous  拍照|拍摄照片和视频|使用摄像头|需要能够访问摄像机装置
+RECORD_AUDIO            dangerous  录音|录制音频|允许应用程序录制音频
+ACCESS_FINE_LOCATION    dangerous  获取精确位置|精确位置|精确的位置信息|允许应用访问精确位置
+ACCESS_COARSE_LOCATION  dangerous  获取粗略位置|大致位置|粗略的位置信息|允许应用程序访问的大致位置
+READ_EXTERNAL_STORAGE   dangerous  读取存储卡|读取存储卡中的内容|读取外部存储|允许应用程序从外部存储读取
+WRITE_EXTERNAL_STORAGE  dangerous  修改或删除存储卡中的内容|写入存储卡|写入外部存储|允许应用程序写入到外部存储器
+BODY_SENSORS            dangerous  身体传感器|访问身体传感器|允许从传感器，用户使用来衡量什么是他/她的身体内部发生的情况，如心脏速率访问数据的应用程序
+
+INTERNET                     normal  访问网络|完全的网络访问权限|允许应用程序打开网络套接字
+ACCESS_NETWORK_STATE         normal  查看网络状态|获取网络状态|查看网络连接|允许程序访问有关网络的信息
+ACCESS_WIFI_STATE            normal  查看WLAN状态|查看WLAN连接|获取WiFi状态|允许应用程序访问Wi-Fi网络的信息
+CHANGE_WIFI_STATE            normal  更改WLAN状态|连接和断开WLAN网络|允许应用程序更改Wi-Fi连接状态
+CHANGE_NETWORK_STATE         normal  更改网络连接|更改网络连接性|允许应用程序改变网络连接状态
+CHANGE_WIFI_MULTICAST_STATE  normal  接收WLAN多播数据包|允许应用程序进入的Wi-Fi多播模式
+MODIFY_AUDIO_SETTINGS        normal  修改音频设置|更改您的音频设置|允许应用程序修改全局音频设置
+RECEIVE_BOOT_COMPLETED       normal  开机启动|开机自动启动|开机时自动启动|允许应用程序接收ACTION_BOOT_COMPLETED*
+VIBRATE                      normal  控制振动|控制震动|允许访问振动
+WAKE_LOCK                    normal  防止手机休眠|防止设备休眠|允许使用PowerManager WakeLocks*
+BLUETOOTH                    normal  使用蓝牙|与蓝牙设备配对|允许应用程序连接到已配对的蓝牙设备
+BLUETOOTH_ADMIN              normal  访问蓝牙设置|蓝牙管理|允许应用程序发现和配对蓝牙设备
+BROADCAST_STICKY             normal  发送持久广播|允许应用程序广播常用意图这些广播数据由该系统被完成之后保持，以便客户端可以快速地检索数据，而不必等待下一个广播
+GET_PACKAGE_SIZE             normal  计算应用存储空间|允许找出任何package占用空间的应用程序
+NFC                          normal  控制近距离通信|控制NFC|允许应用程序通过NFC进行I/O操作
+SET_ALARM                    normal  设置闹钟|允许应用程序广播一个Intent设置为用户报警
+USE_FINGERPRINT              normal  使用指纹硬件|允许应用程序使用指纹硬件
+READ_SYNC_SETTINGS           normal  读取同步设置|允许应用程序读取同步设置
+WRITE_SYNC_SETTINGS          normal  修改同步设置|开启和关闭同步|允许程序写入同步设置
+KILL_BACKGROUND_PROCESSES    normal  结束后台进程|关闭其他应用|允许应用程序调用killBackgroundProcesses*
+PERSISTENT_ACTIVITY          normal  让应用始终运行|允许应用程序使其活动持续
+EXPAND_STATUS_BAR            normal  展开/收拢状态栏|展开或折叠状态栏|允许应用程序展开或折叠状态栏
+DISABLE_KEYGUARD             normal  停用屏幕锁定|禁用键盘锁|允许应用程序禁用键盘锁，如果它是不安全的
+GET_TASKS                    normal  检索正在运行的应用|获取正在运行的应用|允许应用程序获取有关当前或最近运行的任务的信息
+ACCESS_LOCATION_EXTRA_COMMANDS normal 访问额外的位置信息提供程序命令|允许应用程序访问额外的位置提供命令
+com.android.launcher.permission.INSTALL_SHORTCUT    normal  创建快捷方式|安装快捷方式|允许安装在发射器的快捷方式的应用程序
+com.android.launcher.permission.UNINSTALL_SHORTCUT  normal  删除快捷方式|卸载快捷方式|允许应用程序卸载启动的快捷方式
+
+WRITE_SETTINGS                 appop  修改系统设置|读取或写入系统设置|允许应用程序读取或写入系统设置
+SYSTEM_ALERT_WINDOW            appop  显示悬浮窗|在其他应用之上显示内容|显示系统级警报|允许应用程序创建一个使用类型的窗口 TYPE_SYSTEM_ALERT*
+REQUEST_INSTALL_PACKAGES       appop  请求安装应用|请求安装文件包
+
+CHANGE_CONFIGURATION       signature  更改系统显示设置|允许应用程序修改当前设置，如本地化
+READ_LOGS                  signature  读取系统日志|读取敏感日志数据|允许应用程序读取低级别的系统日志文件
+MOUNT_UNMOUNT_FILESYSTEMS  signature  装载和卸载文件系统|允许安装和可移动存储卸载文件系统
+`
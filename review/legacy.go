@@ -0,0 +1,65 @@
+package review
+
+import (
+	"time"
+	"encoding/json"
+)
+
+import (
+	"github.com/Vonng/go-android-search/norm"
+)
+
+// NewsItem 是应用详情页上的一条新闻、技巧或攻略
+type NewsItem struct {
+	Title  string `json:"title"`  // 标题
+	URL    string `json:"url"`    // 链接
+	Source string `json:"source"` // 来源站点，如 `17173`
+}
+
+// 旧数据中评论日期的格式，按顺序尝试
+var legacyDateFmts = []string{"20060102", "2006-01-02"}
+
+func parseLegacyDate(s string) (time.Time, bool) {
+	for _, layout := range legacyDateFmts {
+		if t, err := time.ParseInLocation(layout, s, norm.Location); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// UnmarshalJSON 解析评论，兼容旧数据中的三元组 `[<user>,"YYYYMMDD",<content>]`，
+// 以及按旧注释顺序写入的 `["YYYY-MM-DD",<user>,<content>]`，三元组解析出的评论没有ID
+func (r *Review) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var tuple [3]string
+		if err := json.Unmarshal(data, &tuple); err != nil {
+			return err
+		}
+		user, date := tuple[0], tuple[1]
+		if _, ok := parseLegacyDate(user); ok {
+			if _, ok = parseLegacyDate(date); !ok {
+				user, date = date, user
+			}
+		}
+		*r = Review{User: user, Content: tuple[2]}
+		r.Date, _ = parseLegacyDate(date)
+		return nil
+	}
+	type plain Review
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// UnmarshalJSON 解析新闻，兼容旧数据中的三元组 `[<title>,<url>,<source>]`
+func (n *NewsItem) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var tuple [3]string
+		if err := json.Unmarshal(data, &tuple); err != nil {
+			return err
+		}
+		*n = NewsItem{Title: tuple[0], URL: tuple[1], Source: tuple[2]}
+		return nil
+	}
+	type plain NewsItem
+	return json.Unmarshal(data, (*plain)(n))
+}
@@ -0,0 +1,47 @@
+package review
+
+import (
+	"time"
+	"testing"
+	"encoding/json"
+)
+
+import (
+	"github.com/Vonng/go-android-search/norm"
+)
+
+func TestReview_UnmarshalJSON(t *testing.T) {
+	date := time.Date(2017, 7, 29, 0, 0, 0, 0, norm.Location)
+	for _, data := range []string{
+		`[["孤独的自由","20170729","好用"]]`,
+		`[["2017-07-29","孤独的自由","好用"]]`,
+		`[{"user":"孤独的自由","date":"2017-07-29T00:00:00` + date.Format("Z07:00") + `","content":"好用"}]`,
+	} {
+		var reviews []Review
+		if err := json.Unmarshal([]byte(data), &reviews); err != nil {
+			t.Errorf("%s: %s", data, err)
+			continue
+		}
+		if r := reviews[0]; len(reviews) != 1 || r.User != "孤独的自由" || r.Content != "好用" || !r.Date.Equal(date) {
+			t.Errorf("%s => %+v", data, reviews)
+		}
+	}
+
+	body, _ := json.Marshal(Review{User: "u", Content: "c"})
+	var r Review
+	if err := json.Unmarshal(body, &r); err != nil || r.User != "u" || r.Content != "c" {
+		t.Errorf("round trip = %+v %v", r, err)
+	}
+}
+
+func TestNewsItem_UnmarshalJSON(t *testing.T) {
+	var news []NewsItem
+	data := `[["攻略","http://a.com/1","17173"],{"title":"新闻","url":"http://b.com/2","source":"新浪"}]`
+	if err := json.Unmarshal([]byte(data), &news); err != nil {
+		t.Fatal(err)
+	}
+	want := []NewsItem{{"攻略", "http://a.com/1", "17173"}, {"新闻", "http://b.com/2", "新浪"}}
+	if len(news) != 2 || news[0] != want[0] || news[1] != want[1] {
+		t.Errorf("news = %+v", news)
+	}
+}
@@ -0,0 +1,163 @@
+package review
+
+import (
+	"fmt"
+	"time"
+	"strings"
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+import (
+	"github.com/go-pg/pg"
+)
+
+// Review 是应用的一条用户评论，对应 review 表
+type Review struct {
+	Source      string    `json:"source" sql:",pk"`                // 来源 wdj/sjqq
+	AppID       string    `json:"app_id" sql:",pk"`                // 应用包名
+	ID          string    `json:"id" sql:",pk"`                    // 评论ID，来源未提供时为内容摘要，见 HashID
+	User        string    `json:"user" sql:"user_name"`            // 用户昵称
+	Rating      int       `json:"rating" sql:",notnull"`           // 评分1-5，0表示未知
+	Date        time.Time `json:"date"`                            // 评论时间
+	Content     string    `json:"content"`                         // 评论内容
+	Version     string    `json:"version"`                         // 评论时的应用版本，未知为空
+	Likes       int64     `json:"likes" sql:",notnull"`            // 点赞数
+	CrawledTime time.Time `json:"crawled_time" sql:"crawled_time"` // 抓取时间
+	tableName   struct{}  `sql:"review"`
+}
+
+// HashID 根据评论的若干字段生成稳定的评论ID，用于来源未提供评论ID的情况
+func HashID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Page 是一页评论
+type Page struct {
+	Reviews []Review // 按时间从新到旧排列
+	Next    string   // 下一页游标，为空表示没有下一页
+	Total   int64    // 评论总数，来源未提供时为0
+}
+
+// Fetcher 获取应用的一页评论，cursor 为空表示第一页
+type Fetcher func(app, cursor string) (*Page, error)
+
+// Crawl 从 cursor 处（为空时从最新的评论）开始逐页抓取，stop 对某条评论返回 true 或没有下一页时停止，
+// 只返回 stop 之前的评论。maxPages 不大于0时不限页数；达到最大页数时仍有下一页，则通过 next 返回下一页游标，
+// 调用方应记为 Gap 下次继续，否则该区间再也不会被抓取。第一页之后的页面失败时，返回已抓取的评论与错误
+func Crawl(fetch Fetcher, app, cursor string, stop func(r Review) bool, maxPages int) (reviews []Review, total int64, next string, err error) {
+	for page := 1; maxPages <= 0 || page <= maxPages; page++ {
+		p, err := fetch(app, cursor)
+		if err != nil {
+			if page == 1 {
+				return nil, 0, "", err
+			}
+			return reviews, total, "", fmt.Errorf("review page %d of %s: %s", page, app, err)
+		}
+		if page == 1 {
+			total = p.Total
+		}
+		for _, r := range p.Reviews {
+			if stop != nil && stop(r) {
+				return reviews, total, "", nil
+			}
+			reviews = append(reviews, r)
+		}
+		if p.Next == "" || len(p.Reviews) == 0 {
+			return reviews, total, "", nil
+		}
+		cursor = p.Next
+	}
+	return reviews, total, cursor, nil
+}
+
+// Oldest 返回评论中最早的时间，没有评论时为零值
+func Oldest(reviews []Review) (t time.Time) {
+	for i, r := range reviews {
+		if i == 0 || r.Date.Before(t) {
+			t = r.Date
+		}
+	}
+	return
+}
+
+// Gap 是一段因达到最大页数而未抓完的评论，对应 review_gap 表。
+// 下次从 Cursor 继续抓取，遇到早于 Before 的已知评论时该段补齐。游标为页内偏移时，
+// 期间的新评论会使续抓的页面与已抓取的评论重叠，这些评论不早于 Before，不会误判为补齐
+type Gap struct {
+	Source      string    `sql:",pk"` // 来源 wdj/sjqq
+	AppID       string    `sql:",pk"` // 应用包名
+	CreatedTime time.Time `sql:",pk"` // 产生时间，多段时先补较新的一段
+	Cursor      string    // 下一页游标
+	Before      time.Time // 已抓取的最早评论时间
+	tableName   struct{}  `sql:"review_gap"`
+}
+
+// LoadGap 返回应用最新的一段 Gap，没有时返回 nil
+func LoadGap(db *pg.DB, source, app string) (*Gap, error) {
+	var gaps []Gap
+	err := db.Model(&gaps).Where("source = ? AND app_id = ?", source, app).
+		OrderExpr("created_time DESC").Limit(1).Select()
+	if err != nil || len(gaps) == 0 {
+		return nil, err
+	}
+	return &gaps[0], nil
+}
+
+// Save 写入或更新 Gap
+func (g *Gap) Save(db *pg.DB) error {
+	_, err := db.Model(g).OnConflict("(source, app_id, created_time) DO UPDATE").
+		Set("cursor = EXCLUDED.cursor").
+		Set("before = EXCLUDED.before").
+		Insert()
+	return err
+}
+
+// Delete 删除已补齐的 Gap
+func (g *Gap) Delete(db *pg.DB) error {
+	_, err := db.Exec(`DELETE FROM review_gap WHERE source = ? AND app_id = ? AND created_time = ?;`,
+		g.Source, g.AppID, g.CreatedTime)
+	return err
+}
+
+// Exists 判断评论是否已在 review 表中
+func Exists(db *pg.DB, source, app, id string) (bool, error) {
+	var found bool
+	_, err := db.QueryOne(pg.Scan(&found), `SELECT EXISTS(SELECT 1 FROM review WHERE source = ? AND app_id = ? AND id = ?);`,
+		source, app, id)
+	return found, err
+}
+
+// KnownIDs 返回数据库中应用最新的n条评论ID，用于增量抓取
+func KnownIDs(db *pg.DB, source, app string, n int) (map[string]bool, error) {
+	var ids []string
+	_, err := db.Query(&ids, `SELECT id FROM review WHERE source = ? AND app_id = ? ORDER BY date DESC LIMIT ?;`,
+		source, app, n)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		known[id] = true
+	}
+	return known, nil
+}
+
+// Save 将评论写入 review 表，已存在的评论会被忽略，返回新写入的条数
+func Save(db *pg.DB, reviews []Review) (int, error) {
+	if len(reviews) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	for i := range reviews {
+		if reviews[i].CrawledTime.IsZero() {
+			reviews[i].CrawledTime = now
+		}
+	}
+	res, err := db.Model(&reviews).OnConflict("DO NOTHING").Insert()
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
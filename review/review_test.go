@@ -0,0 +1,75 @@
+package review
+
+import (
+	"time"
+	"errors"
+	"testing"
+)
+
+func TestCrawl(t *testing.T) {
+	pages := map[string]*Page{
+		"":   {Reviews: []Review{{ID: "5"}, {ID: "4"}}, Next: "p2", Total: 5},
+		"p2": {Reviews: []Review{{ID: "3"}, {ID: "2"}}, Next: "p3"},
+		"p3": {Reviews: []Review{{ID: "1"}}},
+	}
+	fetch := func(app, cursor string) (*Page, error) {
+		if p, ok := pages[cursor]; ok {
+			return p, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	for _, max := range []int{10, 0} {
+		reviews, total, next, err := Crawl(fetch, "a", "", nil, max)
+		if err != nil || len(reviews) != 5 || total != 5 || next != "" {
+			t.Errorf("full crawl of %d pages = %d %d %q %v", max, len(reviews), total, next, err)
+		}
+	}
+
+	// 增量抓取: 遇到已知评论即停止
+	known := map[string]bool{"3": true, "2": true, "1": true}
+	stop := func(r Review) bool { return known[r.ID] }
+	reviews, _, next, err := Crawl(fetch, "a", "", stop, 10)
+	if err != nil || len(reviews) != 2 || reviews[1].ID != "4" || next != "" {
+		t.Errorf("incremental crawl = %v %q %v", reviews, next, err)
+	}
+
+	// 达到最大页数时返回下一页游标，从游标继续可抓完其余评论
+	reviews, _, next, err = Crawl(fetch, "a", "", nil, 1)
+	if err != nil || len(reviews) != 2 || next != "p2" {
+		t.Errorf("capped crawl = %d %q %v", len(reviews), next, err)
+	}
+	if reviews, _, next, err = Crawl(fetch, "a", next, nil, 1); len(reviews) != 2 || reviews[0].ID != "3" || next != "p3" {
+		t.Errorf("resumed crawl = %v %q %v", reviews, next, err)
+	}
+	// 在最后一页达到最大页数，没有下一页则不返回游标
+	if _, _, next, _ = Crawl(fetch, "a", "p2", nil, 2); next != "" {
+		t.Errorf("crawl to last page returns cursor %q", next)
+	}
+	// 遇到已知评论时即使达到最大页数也不返回游标
+	if _, _, next, _ = Crawl(fetch, "a", "", func(r Review) bool { return r.ID == "3" }, 2); next != "" {
+		t.Errorf("crawl stopped at known review returns cursor %q", next)
+	}
+
+	pages["p2"].Next = "p9"
+	reviews, _, _, err = Crawl(fetch, "a", "", nil, 10)
+	if err == nil || len(reviews) != 4 {
+		t.Errorf("partial crawl = %d %v", len(reviews), err)
+	}
+}
+
+func TestOldest(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 8, d, 0, 0, 0, 0, time.UTC) }
+	if oldest := Oldest([]Review{{Date: day(3)}, {Date: day(1)}, {Date: day(2)}}); !oldest.Equal(day(1)) {
+		t.Errorf("oldest = %s", oldest)
+	}
+	if oldest := Oldest(nil); !oldest.IsZero() {
+		t.Errorf("oldest of none = %s", oldest)
+	}
+}
+
+func TestHashID(t *testing.T) {
+	if HashID("a", "b") == HashID("ab", "") || HashID("a", "b") != HashID("a", "b") {
+		t.Error("hash id should be stable and separate fields")
+	}
+}
@@ -0,0 +1,315 @@
+package richtext
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 详情页的描述、编辑评论与更新日志为 HTML 片段，常见 `<br>`、`</br>`、`&amp;` 与嵌套的 span
+// Text 与 Markdown 按浏览器的方式折叠空白、分段与换行，Sanitize 保留安全的标签与属性
+
+// dropped 为连同内容一起丢弃的元素
+var dropped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Head: true, atom.Title: true,
+	atom.Form: true, atom.Input: true, atom.Button: true, atom.Select: true, atom.Textarea: true,
+}
+
+// blocks 为块级元素，前后各成一段
+var blocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Header: true, atom.Footer: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Table: true, atom.Tr: true, atom.Hr: true,
+}
+
+// Parse 将 HTML 片段解析为节点列表，片段按 body 中的内容解析
+func Parse(fragment string) ([]*html.Node, error) {
+	return html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+}
+
+// Text 将 HTML 片段转为纯文本：解码实体，折叠空白，`<br>` 为换行，块级元素之间空一行，
+// 列表项以 `- ` 或 `1. ` 开头，丢弃脚本与样式。无法解析时返回去掉首尾空白的原文
+func Text(fragment string) string {
+	return convert(fragment, false)
+}
+
+// Markdown 与 Text 相同，另外将标题、粗体、斜体、链接、图片、代码与引用转为 Markdown 语法
+func Markdown(fragment string) string {
+	return convert(fragment, true)
+}
+
+func convert(fragment string, markdown bool) string {
+	nodes, err := Parse(fragment)
+	if err != nil {
+		return strings.TrimSpace(fragment)
+	}
+	w := &writer{markdown: markdown}
+	for _, n := range nodes {
+		w.node(n)
+	}
+	return w.String()
+}
+
+// writer 逐个节点输出文本，换行延迟到下一段内容之前输出，避免首尾与连续的空行
+type writer struct {
+	buf      bytes.Buffer
+	markdown bool
+	pending  int    // 待输出的换行数，块级元素取较大者，br 累加
+	space    bool   // 待输出一个空格
+	prefix   string // 每行的前缀，如引用的 `> `
+	pre      int    // 处于 pre 中的层数，不折叠空白
+	item     bool   // 刚输出列表项标记，项内第一个块级元素不再换行
+}
+
+var (
+	pSpaces   = regexp.MustCompile(`[ \t\r\n\f]+`)
+	pNewlines = regexp.MustCompile(`\n{3,}`)
+)
+
+// text 输出一段文本，pre 之外的空白折叠为一个空格
+func (w *writer) text(s string) {
+	if w.pre > 0 {
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				w.pending++
+			}
+			w.write(line)
+		}
+		return
+	}
+	s = pSpaces.ReplaceAllString(s, " ")
+	if strings.HasPrefix(s, " ") {
+		w.space = true
+	}
+	trailing := strings.HasSuffix(s, " ")
+	if s = strings.TrimSpace(s); s != "" {
+		if w.markdown {
+			s = escape(s)
+		}
+		w.write(s)
+	}
+	w.space = w.space || trailing
+}
+
+// write 先补齐待输出的换行或空格再输出内容，文档开头的换行与空格被丢弃
+func (w *writer) write(s string) {
+	if s == "" {
+		return
+	}
+	switch {
+	case w.buf.Len() == 0:
+		w.buf.WriteString(w.prefix)
+	case w.pending > 0:
+		for i := 0; i < w.pending; i++ {
+			w.buf.WriteString("\n" + w.prefix)
+		}
+	case w.space:
+		w.buf.WriteByte(' ')
+	}
+	w.pending, w.space, w.item = 0, false, false
+	w.buf.WriteString(s)
+}
+
+// newline 请求 n 个换行，已有请求时取较大者
+func (w *writer) newline(n int) {
+	if w.item {
+		return
+	}
+	if n > w.pending {
+		w.pending = n
+	}
+	w.space = false
+}
+
+// String 返回结果，去掉行尾空白，连续的空行只保留一个
+func (w *writer) String() string {
+	lines := strings.Split(w.buf.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return pNewlines.ReplaceAllString(strings.TrimSpace(strings.Join(lines, "\n")), "\n\n")
+}
+
+func (w *writer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+func (w *writer) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.DocumentNode:
+		w.children(n)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if dropped[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		w.pending++
+		w.space = false
+		return
+	case atom.Li:
+		w.newline(1)
+		marker := "- "
+		if n.Parent != nil && n.Parent.DataAtom == atom.Ol {
+			marker = strconv.Itoa(index(n)) + ". "
+		}
+		w.write(marker)
+		w.item = true
+		w.children(n)
+		w.item = false
+		w.newline(1)
+		return
+	case atom.Img:
+		if w.markdown {
+			if src := attr(n, "src"); safeURL(src) {
+				w.write("![" + escape(attr(n, "alt")) + "](" + src + ")")
+			}
+		}
+		return
+	}
+
+	if w.markdown {
+		switch n.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			w.newline(2)
+			w.write(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+			w.children(n)
+			w.newline(2)
+			return
+		case atom.B, atom.Strong:
+			w.wrap(n, "**")
+			return
+		case atom.I, atom.Em:
+			w.wrap(n, "*")
+			return
+		case atom.Code:
+			if w.pre == 0 {
+				w.wrap(n, "`")
+				return
+			}
+		case atom.A:
+			if href := attr(n, "href"); safeURL(href) && href != "" {
+				w.write("[")
+				w.children(n)
+				w.write("](" + href + ")")
+				return
+			}
+		case atom.Blockquote:
+			w.newline(2)
+			prefix := w.prefix
+			w.prefix += "> "
+			w.children(n)
+			w.prefix = prefix
+			w.newline(2)
+			return
+		case atom.Pre:
+			w.newline(2)
+			w.write("```")
+			w.newline(1)
+			w.pre++
+			w.children(n)
+			w.pre--
+			w.newline(1)
+			w.write("```")
+			w.newline(2)
+			return
+		case atom.Hr:
+			w.newline(2)
+			w.write("---")
+			w.newline(2)
+			return
+		}
+	}
+
+	if n.DataAtom == atom.Pre {
+		w.pre++
+		defer func() { w.pre-- }()
+	}
+	if n.DataAtom == atom.Td || n.DataAtom == atom.Th {
+		w.space = true
+	}
+	if blocks[n.DataAtom] {
+		w.newline(paragraph(n))
+		w.children(n)
+		w.newline(paragraph(n))
+		return
+	}
+	w.children(n)
+}
+
+// wrap 以 mark 包围元素的内容，内容为空时不输出
+func (w *writer) wrap(n *html.Node, mark string) {
+	size, pending, space := w.buf.Len(), w.pending, w.space
+	w.write(mark)
+	start := w.buf.Len()
+	w.children(n)
+	if w.buf.Len() == start {
+		w.buf.Truncate(size)
+		w.pending, w.space = pending, space
+		return
+	}
+	w.buf.WriteString(mark)
+}
+
+// paragraph 返回块级元素前后的换行数，列表与表格中的元素只换行
+func paragraph(n *html.Node) int {
+	switch n.DataAtom {
+	case atom.Div, atom.Tr, atom.Dt, atom.Dd:
+		return 1
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.Li || p.DataAtom == atom.Table {
+			return 1
+		}
+	}
+	return 2
+}
+
+// index 返回列表项在列表中的序号，从 1 开始
+func index(li *html.Node) int {
+	i := 1
+	for s := li.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode && s.DataAtom == atom.Li {
+			i++
+		}
+	}
+	return i
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+// escaper 转义 Markdown 中有特殊含义的字符
+var escaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
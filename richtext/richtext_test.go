@@ -0,0 +1,83 @@
+package richtext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"", ""},
+		{"  纯文本  ", "纯文本"},
+		{"a &amp; b &gt; c &#34;d&#34;", `a & b > c "d"`},
+		{"第一行<br>第二行<br/>第三行", "第一行\n第二行\n第三行"},
+		{"第一行</br></br>第二行", "第一行\n\n第二行"},
+		{"a<br><br><br><br>b", "a\n\nb"},
+		{"<p>第一段</p><p>第二段</p>", "第一段\n\n第二段"},
+		{"<div>a</div><div>b</div>", "a\nb"},
+		{"<span>多个\n  空白\t折叠</span>", "多个 空白 折叠"},
+		{"<b>粗</b>体<i>斜</i>", "粗体斜"},
+		{"<ul><li>一</li><li>二</li></ul>", "- 一\n- 二"},
+		{"前<ol><li>一</li><li><p>二</p></li></ol>后", "前\n\n1. 一\n2. 二\n\n后"},
+		{"a<script>alert(1)</script><style>p{}</style>b", "ab"},
+		{"<pre>x  y\n  z</pre>", "x  y\n  z"},
+		{"<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>", "a b\nc"},
+		{"<p>未闭合<span>标签", "未闭合标签"},
+	}
+	for _, c := range cases {
+		if got := Text(c.in); got != c.want {
+			t.Errorf("Text(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"<h2>更新</h2><p>修复<b>闪退</b>与<em>卡顿</em></p>", "## 更新\n\n修复**闪退**与*卡顿*"},
+		{`<a href="http://x.com/a">官网</a>`, "[官网](http://x.com/a)"},
+		{`<a href="javascript:alert(1)">链接</a>`, "链接"},
+		{`<img src="http://x.com/1.png" alt="图">`, "![图](http://x.com/1.png)"},
+		{"1*2_3 [x]", `1\*2\_3 \[x\]`},
+		{"<code>a_b</code>", "`a\\_b`"},
+		{"<b> </b>空", "空"},
+		{"<blockquote>引用<br>两行</blockquote>", "> 引用\n> 两行"},
+		{"<ol><li>一</li><li>二</li></ol>", "1. 一\n2. 二"},
+		{"<hr>", "---"},
+	}
+	for _, c := range cases {
+		if got := Markdown(c.in); got != c.want {
+			t.Errorf("Markdown(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"a &amp; b<br>c", "a &amp; b<br>c"},
+		{"a</br>b", "a<br>b"},
+		{`<p onclick="x()" class="c">段落</p>`, "<p>段落</p>"},
+		{"a<script>alert(1)</script>b", "ab"},
+		{`<font color="red">红</font>`, "红"},
+		{`<a href="http://x.com" target="_blank">链接</a>`, `<a href="http://x.com" rel="nofollow noopener">链接</a>`},
+		{`<a href="JavaScript:alert(1)">链接</a>`, "<a>链接</a>"},
+		{`<img src="data:image/png;base64,xx" alt="图">`, `<img alt="图">`},
+		{`<img src="/1.png" onerror="x()">`, `<img src="/1.png">`},
+		{`<td title="t">格</td>`, "格"},
+		{"<!-- 注释 -->文本", "文本"},
+	}
+	for _, c := range cases {
+		if got := Sanitize(c.in); got != c.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	// 清理结果再次清理不变，纯文本与原结果一致
+	in := "<div>修复<b>若干</b>问题</br><ul><li>一</li></ul><iframe src=x></iframe></div>"
+	once := Sanitize(in)
+	if twice := Sanitize(once); twice != once {
+		t.Errorf("Sanitize not idempotent: %q -> %q", once, twice)
+	}
+	if Text(once) != Text(in) || strings.Contains(once, "iframe") {
+		t.Errorf("Sanitize(%q) = %q", in, once)
+	}
+}
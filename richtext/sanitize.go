@@ -0,0 +1,96 @@
+package richtext
+
+import (
+	"bytes"
+	"strings"
+)
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowed 为 Sanitize 保留的元素及其可保留的属性，其余元素去掉标签保留内容
+var allowed = map[atom.Atom][]string{
+	atom.P: nil, atom.Br: nil, atom.Div: nil, atom.Span: nil, atom.Hr: nil,
+	atom.H1: nil, atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.Ul: nil, atom.Ol: nil, atom.Li: nil, atom.Dl: nil, atom.Dt: nil, atom.Dd: nil,
+	atom.B: nil, atom.Strong: nil, atom.I: nil, atom.Em: nil, atom.U: nil, atom.S: nil,
+	atom.Sub: nil, atom.Sup: nil, atom.Blockquote: nil, atom.Pre: nil, atom.Code: nil,
+	atom.Table: nil, atom.Thead: nil, atom.Tbody: nil, atom.Tr: nil,
+	atom.Td: {"colspan", "rowspan"}, atom.Th: {"colspan", "rowspan"},
+	atom.A:   {"href", "title"},
+	atom.Img: {"src", "alt", "title", "width", "height"},
+}
+
+// Sanitize 清理 HTML 片段：丢弃脚本、样式、表单与内嵌对象，去掉不在白名单中的标签与属性，
+// 链接与图片只保留 http、https 与相对地址，链接加上 rel="nofollow noopener"，实体按需重新转义。
+// `</br>` 等不规范的写法按浏览器的方式修正。无法解析时返回转义后的原文
+func Sanitize(fragment string) string {
+	nodes, err := Parse(fragment)
+	if err != nil {
+		return html.EscapeString(strings.TrimSpace(fragment))
+	}
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		sanitize(&buf, n)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+func sanitize(buf *bytes.Buffer, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		buf.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		// 注释、doctype 等
+		return
+	}
+	if dropped[n.DataAtom] {
+		return
+	}
+
+	attrs, ok := allowed[n.DataAtom]
+	if ok {
+		buf.WriteString("<" + n.DataAtom.String())
+		link := false
+		for _, key := range attrs {
+			v := attr(n, key)
+			if v == "" || (key == "href" || key == "src") && !safeURL(v) {
+				continue
+			}
+			link = link || key == "href"
+			buf.WriteString(" " + key + `="` + html.EscapeString(v) + `"`)
+		}
+		if link {
+			buf.WriteString(` rel="nofollow noopener"`)
+		}
+		buf.WriteString(">")
+	}
+	switch n.DataAtom {
+	case atom.Br, atom.Hr, atom.Img:
+		return // void 元素
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitize(buf, c)
+	}
+	if ok {
+		buf.WriteString("</" + n.DataAtom.String() + ">")
+	}
+}
+
+// safeURL 判断链接是否可以保留：http、https 或不带协议的相对地址
+func safeURL(u string) bool {
+	u = strings.ToLower(strings.TrimSpace(u))
+	i := strings.IndexAny(u, ":/?#")
+	if i < 0 || u[i] != ':' {
+		return true
+	}
+	switch u[:i] {
+	case "http", "https":
+		return true
+	}
+	return false
+}
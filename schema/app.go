@@ -0,0 +1,215 @@
+package schema
+
+import (
+	"time"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vonng/go-android-search/fill"
+	"github.com/Vonng/go-android-search/norm"
+)
+
+// AppTypes 为视作应用的 schema.org 类型
+var AppTypes = []string{"SoftwareApplication", "MobileApplication", "WebApplication", "VideoGame"}
+
+// Offer 为应用的一个报价
+type Offer struct {
+	Price         float64
+	PriceCurrency string
+}
+
+// SoftwareApplication 为页面上以 microdata 或 JSON-LD 标注的应用信息，文本保留原文，由各商店自行解析
+type SoftwareApplication struct {
+	Name             string
+	Description      string
+	Image            string
+	URL              string
+	Version          string   // softwareVersion
+	OperatingSystem  string   // operatingSystem，如 `Android 4.0`
+	Category         []string // applicationCategory
+	FileSize         string   // 如 `43.08MB`
+	DatePublished    string   // 如 `2017-07-05`，豌豆荚为 `2017年07月05日`
+	Author           string   // author 或 publisher 的名称
+	RatingValue      float64  // aggregateRating.ratingValue
+	BestRating       float64  // aggregateRating.bestRating，缺省为 5
+	RatingCount      int64    // aggregateRating.ratingCount
+	ReviewCount      int64    // aggregateRating.reviewCount 或 reviewCount
+	InteractionCount string   // 下载次数，如 `21.5亿`，已去除 `UserDownloads:` 前缀
+	Screenshots      []string
+	Permissions      []string
+	Offers           []Offer
+}
+
+// Find 返回文档中标注的应用，microdata 优先，JSON-LD 补充其中为空的值，都没有时返回 nil
+func Find(doc *goquery.Document) *SoftwareApplication {
+	var found *SoftwareApplication
+	for _, items := range [][]*Item{Microdata(doc), JSONLD(doc)} {
+		for _, item := range items {
+			if item.Is(AppTypes...) {
+				found = found.merge(FromItem(item))
+				break
+			}
+		}
+	}
+	return found
+}
+
+// FromItem 将一个应用实体转为 SoftwareApplication
+func FromItem(item *Item) *SoftwareApplication {
+	app := &SoftwareApplication{
+		Name:            text(item, "name"),
+		Description:     text(item, "description"),
+		Image:           text(item, "image"),
+		URL:             text(item, "url"),
+		Version:         text(item, "softwareVersion"),
+		OperatingSystem: first(text(item, "operatingSystem"), text(item, "operatingSystems")),
+		Category:        texts(item, "applicationCategory"),
+		FileSize:        text(item, "fileSize"),
+		DatePublished:   first(text(item, "datePublished"), text(item, "dateModified")),
+		Author:          first(text(item, "author"), text(item, "publisher")),
+		ReviewCount:     parseInt(text(item, "reviewCount")),
+		Screenshots:     texts(item, "screenshot"),
+		Permissions:     texts(item, "permissions"),
+	}
+
+	if rating := item.Item("aggregateRating"); rating != nil {
+		app.RatingValue = parseFloat(rating.Text("ratingValue"))
+		app.BestRating = parseFloat(rating.Text("bestRating"))
+		app.RatingCount = parseInt(rating.Text("ratingCount"))
+		if n := parseInt(rating.Text("reviewCount")); n > 0 {
+			app.ReviewCount = n
+		}
+	}
+
+	// interactionCount 为旧写法 `UserDownloads:2.2亿`，interactionStatistic 为新写法
+	if count := item.Text("interactionCount"); count != "" {
+		app.InteractionCount = count[strings.LastIndexByte(count, ':')+1:]
+	}
+	if stat := item.Item("interactionStatistic"); stat != nil && app.InteractionCount == "" {
+		app.InteractionCount = stat.Text("userInteractionCount")
+	}
+
+	for _, v := range item.Props["offers"] {
+		if v.Item == nil {
+			continue
+		}
+		price := first(v.Item.Text("price"), v.Item.Text("lowPrice"))
+		if f, err := strconv.ParseFloat(price, 64); err == nil {
+			app.Offers = append(app.Offers, Offer{f, v.Item.Text("priceCurrency")})
+		}
+	}
+	return app
+}
+
+// Rating 返回百分制评分，没有评分时返回 0
+func (app *SoftwareApplication) Rating() int64 {
+	best := app.BestRating
+	if best <= 0 {
+		best = 5
+	}
+	if app.RatingValue <= 0 || app.RatingValue > best {
+		return 0
+	}
+	return int64(app.RatingValue * 100 / best)
+}
+
+// dateLayouts 为 datePublished 常见的格式
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02", "2006年01月02日", "2006/01/02"}
+
+// ReleaseTime 解析 DatePublished，未注明时区时为北京时间，无法解析时返回零值
+func (app *SoftwareApplication) ReleaseTime() time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, app.DatePublished, norm.Location); err == nil {
+			return t
+		}
+	}
+	t, _ := norm.Date(app.DatePublished)
+	return t
+}
+
+// Price 返回第一个报价，单位为分
+func (app *SoftwareApplication) Price() int64 {
+	if len(app.Offers) == 0 {
+		return 0
+	}
+	return int64(app.Offers[0].Price*100 + 0.5)
+}
+
+// merge 用 other 填充 app 中为空的值，app 为 nil 时返回 other
+func (app *SoftwareApplication) merge(other *SoftwareApplication) *SoftwareApplication {
+	if app == nil {
+		return other
+	}
+	fill.String(&app.Name, other.Name)
+	fill.String(&app.Description, other.Description)
+	fill.String(&app.Image, other.Image)
+	fill.String(&app.URL, other.URL)
+	fill.String(&app.Version, other.Version)
+	fill.String(&app.OperatingSystem, other.OperatingSystem)
+	fill.String(&app.FileSize, other.FileSize)
+	fill.String(&app.DatePublished, other.DatePublished)
+	fill.String(&app.Author, other.Author)
+	fill.String(&app.InteractionCount, other.InteractionCount)
+	fill.List(&app.Category, other.Category)
+	fill.List(&app.Screenshots, other.Screenshots)
+	fill.List(&app.Permissions, other.Permissions)
+	if app.RatingValue == 0 {
+		app.RatingValue, app.BestRating = other.RatingValue, other.BestRating
+	}
+	fill.Int(&app.RatingCount, other.RatingCount)
+	fill.Int(&app.ReviewCount, other.ReviewCount)
+	if len(app.Offers) == 0 {
+		app.Offers = other.Offers
+	}
+	return app
+}
+
+// text 返回属性的文本，嵌套实体取其 url 或 name，如 ImageObject、Person
+func text(item *Item, prop string) string {
+	if s := item.Text(prop); s != "" {
+		return s
+	}
+	if it := item.Item(prop); it != nil {
+		return first(it.Text("url"), it.Text("contentUrl"), it.Text("name"))
+	}
+	return ""
+}
+
+// texts 返回属性所有文本，嵌套实体同 text
+func texts(item *Item, prop string) (list []string) {
+	for _, v := range item.Props[prop] {
+		s := v.Text
+		if v.Item != nil {
+			s = first(v.Item.Text("url"), v.Item.Text("contentUrl"), v.Item.Text("name"))
+		}
+		if s != "" {
+			list = append(list, s)
+		}
+	}
+	return
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func parseInt(s string) int64 {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		i = int64(parseFloat(s))
+	}
+	return i
+}
@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"strings"
+	"encoding/json"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+)
+
+// JSONLD 返回文档中 `<script type="application/ld+json">` 里的实体，展开顶层数组与 @graph
+// 无法解析的脚本被忽略
+func JSONLD(doc *goquery.Document) (items []*Item) {
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(s.Text()))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return
+		}
+		items = append(items, jsonldItems(v)...)
+	})
+	return
+}
+
+// jsonldItems 将解析后的 JSON 转为实体
+func jsonldItems(v interface{}) (items []*Item) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, e := range v {
+			items = append(items, jsonldItems(e)...)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return jsonldItems(graph)
+		}
+		items = append(items, jsonldItem(v))
+	}
+	return
+}
+
+func jsonldItem(obj map[string]interface{}) *Item {
+	item := newItem()
+	for key, v := range obj {
+		switch key {
+		case "@type":
+			for _, t := range jsonldValues(v) {
+				if t.Text != "" {
+					item.Type = append(item.Type, t.Text)
+				}
+			}
+		case "@context", "@id":
+		default:
+			item.Props[key] = append(item.Props[key], jsonldValues(v)...)
+		}
+	}
+	return item
+}
+
+// jsonldValues 将 JSON 值转为属性值，数组展开为多个值，对象为嵌套实体
+func jsonldValues(v interface{}) (values []Value) {
+	switch v := v.(type) {
+	case string:
+		values = append(values, Value{Text: strings.TrimSpace(v)})
+	case json.Number:
+		values = append(values, Value{Text: v.String()})
+	case bool:
+		if v {
+			values = append(values, Value{Text: "true"})
+		} else {
+			values = append(values, Value{Text: "false"})
+		}
+	case []interface{}:
+		for _, e := range v {
+			values = append(values, jsonldValues(e)...)
+		}
+	case map[string]interface{}:
+		// {"@value": "..."} 为带类型的字面量
+		if literal, ok := v["@value"]; ok {
+			return jsonldValues(literal)
+		}
+		values = append(values, Value{Item: jsonldItem(v)})
+	}
+	return
+}
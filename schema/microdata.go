@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"strings"
+)
+
+import (
+	"golang.org/x/net/html"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Item 为一个 schema.org 实体，来自 microdata 的 itemscope 或 JSON-LD 的对象
+type Item struct {
+	Type  []string           // 类型，如 `http://schema.org/SoftwareApplication`
+	Props map[string][]Value // 属性，同名属性可出现多次
+}
+
+// Value 为属性值，嵌套实体时 Item 非空
+type Value struct {
+	Text string
+	Item *Item
+}
+
+func newItem() *Item {
+	return &Item{Props: make(map[string][]Value)}
+}
+
+// Is 判断实体是否为给定类型之一，比较类型名的最后一段且忽略大小写
+// 如 `http://schema.org/mobileapplication` 是 `MobileApplication`
+func (it *Item) Is(types ...string) bool {
+	for _, t := range it.Type {
+		if i := strings.LastIndexAny(t, "/#:"); i >= 0 {
+			t = t[i+1:]
+		}
+		for _, want := range types {
+			if strings.EqualFold(t, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Text 返回属性的第一个非空文本值
+func (it *Item) Text(prop string) string {
+	for _, v := range it.Props[prop] {
+		if v.Text != "" {
+			return v.Text
+		}
+	}
+	return ""
+}
+
+// Texts 返回属性所有非空文本值
+func (it *Item) Texts(prop string) (texts []string) {
+	for _, v := range it.Props[prop] {
+		if v.Text != "" {
+			texts = append(texts, v.Text)
+		}
+	}
+	return
+}
+
+// Item 返回属性的第一个嵌套实体
+func (it *Item) Item(prop string) *Item {
+	for _, v := range it.Props[prop] {
+		if v.Item != nil {
+			return v.Item
+		}
+	}
+	return nil
+}
+
+// Microdata 返回文档中的顶层 microdata 实体，即不作为其他实体属性的 itemscope 元素
+func Microdata(doc *goquery.Document) (items []*Item) {
+	doc.Find("[itemscope]").Each(func(i int, s *goquery.Selection) {
+		if _, isProp := s.Attr("itemprop"); isProp && s.ParentsFiltered("[itemscope]").Length() > 0 {
+			return
+		}
+		items = append(items, microdataItem(s))
+	})
+	return
+}
+
+// microdataItem 收集 itemscope 元素下的属性，不进入嵌套实体的内部
+func microdataItem(s *goquery.Selection) *Item {
+	item := newItem()
+	if t, ok := s.Attr("itemtype"); ok {
+		item.Type = strings.Fields(t)
+	}
+	var walk func(*goquery.Selection)
+	walk = func(parent *goquery.Selection) {
+		parent.Children().Each(func(i int, c *goquery.Selection) {
+			_, scope := c.Attr("itemscope")
+			if props, ok := c.Attr("itemprop"); ok {
+				v := Value{}
+				if scope {
+					v.Item = microdataItem(c)
+				} else {
+					v.Text = propValue(c)
+				}
+				for _, prop := range strings.Fields(props) {
+					item.Props[prop] = append(item.Props[prop], v)
+				}
+			}
+			if !scope {
+				walk(c)
+			}
+		})
+	}
+	walk(s)
+	return item
+}
+
+// propValue 按 microdata 规范取属性值，另外任意元素上的 content 属性优先，
+// 如豌豆荚的 `<i itemprop="interactionCount" content="UserDownloads:2.2亿">`
+func propValue(s *goquery.Selection) string {
+	if v, ok := s.Attr("content"); ok {
+		return strings.TrimSpace(v)
+	}
+	attr := ""
+	if node := s.Get(0); node != nil && node.Type == html.ElementNode {
+		switch node.Data {
+		case "audio", "embed", "iframe", "img", "source", "track", "video":
+			attr = "src"
+		case "a", "area", "link":
+			attr = "href"
+		case "object":
+			attr = "data"
+		case "data", "meter":
+			attr = "value"
+		case "time":
+			attr = "datetime"
+		}
+	}
+	if attr != "" {
+		if v, ok := s.Attr(attr); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return strings.TrimSpace(s.Text())
+}
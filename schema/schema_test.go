@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+)
+
+const microdataPage = `<html><body>
+<div itemscope itemtype="http://data-vocabulary.org/Breadcrumb"><span itemprop="title">应用</span></div>
+<div itemscope itemtype="http://schema.org/mobileapplication">
+	<meta itemprop="url" content="http://www.wandoujia.com/apps/com.tencent.mm">
+	<img itemprop="image" src="http://icon.png">
+	<span itemprop="name">微信</span>
+	<i itemprop="interactionCount" content="UserDownloads:21.5亿"></i>
+	<div itemprop="aggregateRating" itemscope itemtype="http://schema.org/AggregateRating">
+		<meta itemprop="ratingValue" content="4.5"><meta itemprop="ratingCount" content="1200">
+		<span itemprop="name">nested name is not app name</span>
+	</div>
+	<meta itemprop="fileSize" content="43.08MB"/>
+	<time itemprop="datePublished" datetime="2017年07月05日">2017年07月05日</time>
+	<dd itemprop="operatingSystems" content="Android">Android 4.0 以上</dd>
+	<span itemprop="permissions">网络</span><span itemprop="permissions">相机</span>
+	<a itemprop="screenshot" href="http://1.jpg"></a>
+</div>
+<script type="application/ld+json">
+{"@context": "http://schema.org", "@graph": [
+	{"@type": "Organization", "name": "Tencent"},
+	{"@type": ["SoftwareApplication"], "name": "WeChat", "softwareVersion": "6.5.10",
+	 "author": {"@type": "Organization", "name": "腾讯"},
+	 "aggregateRating": {"@type": "AggregateRating", "ratingValue": 3, "reviewCount": "88"},
+	 "offers": [{"@type": "Offer", "price": "1.99", "priceCurrency": "CNY"}]}
+]}
+</script>
+<script type="application/ld+json">{broken</script>
+</body></html>`
+
+func document(t *testing.T, page string) *goquery.Document {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestMicrodata(t *testing.T) {
+	items := Microdata(document(t, microdataPage))
+	if len(items) != 2 {
+		t.Fatalf("got %d top level items, want 2", len(items))
+	}
+	app := items[1]
+	if !app.Is(AppTypes...) || items[0].Is(AppTypes...) {
+		t.Errorf("types: %v %v", items[0].Type, app.Type)
+	}
+	if got := app.Texts("name"); len(got) != 1 || got[0] != "微信" {
+		t.Errorf("name = %v, nested item properties should not leak", got)
+	}
+	if rating := app.Item("aggregateRating"); rating == nil || rating.Text("ratingValue") != "4.5" {
+		t.Errorf("aggregateRating = %+v", rating)
+	}
+	if got := app.Text("image"); got != "http://icon.png" {
+		t.Errorf("image = %s", got)
+	}
+	if got := app.Text("datePublished"); got != "2017年07月05日" {
+		t.Errorf("datePublished = %s", got)
+	}
+}
+
+func TestJSONLD(t *testing.T) {
+	items := JSONLD(document(t, microdataPage))
+	if len(items) != 2 || !items[1].Is("SoftwareApplication") {
+		t.Fatalf("items = %+v", items)
+	}
+	if got := items[1].Item("offers"); got == nil || got.Text("price") != "1.99" {
+		t.Errorf("offers = %+v", got)
+	}
+}
+
+func TestFind(t *testing.T) {
+	app := Find(document(t, microdataPage))
+	if app == nil {
+		t.Fatal("app not found")
+	}
+	// microdata 优先，JSON-LD 补充
+	if app.Name != "微信" || app.Version != "6.5.10" || app.Author != "腾讯" {
+		t.Errorf("merge: %+v", app)
+	}
+	if app.Rating() != 90 || app.RatingCount != 1200 || app.ReviewCount != 88 {
+		t.Errorf("rating: %+v", app)
+	}
+	if app.InteractionCount != "21.5亿" || app.FileSize != "43.08MB" || app.OperatingSystem != "Android" {
+		t.Errorf("counts: %+v", app)
+	}
+	if len(app.Permissions) != 2 || len(app.Screenshots) != 1 || app.Price() != 199 {
+		t.Errorf("lists: %+v", app)
+	}
+	if rt := app.ReleaseTime(); rt.Year() != 2017 || rt.Month() != 7 || rt.Day() != 5 {
+		t.Errorf("release time = %v", rt)
+	}
+
+	if Find(document(t, `<html><div itemscope itemtype="http://schema.org/Person"></div></html>`)) != nil {
+		t.Error("page without app should return nil")
+	}
+	only := Find(document(t, `<script type="application/ld+json">{"@type":"VideoGame","name":"x"}</script>`))
+	if only == nil || only.Name != "x" || only.Rating() != 0 {
+		t.Errorf("json-ld only: %+v", only)
+	}
+}
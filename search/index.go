@@ -0,0 +1,273 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"strings"
+)
+
+import (
+	"github.com/go-pg/pg"
+)
+
+// 参与检索的字段及其权重
+const (
+	fieldName = iota
+	fieldSubtitle
+	fieldTags
+	fieldVendor
+	fieldDescription
+	fieldCount
+)
+
+var fieldWeights = [fieldCount]float64{
+	fieldName:        5.0,
+	fieldSubtitle:    2.0,
+	fieldTags:        2.0,
+	fieldVendor:      1.5,
+	fieldDescription: 1.0,
+}
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Doc 是索引中的一个应用，同一包名在各来源的记录会合并为一个Doc
+type Doc struct {
+	ID          string
+	Sources     []string
+	Name        string
+	Subtitle    string
+	Description string
+	Tags        []string
+	Vendor      string
+	InstallCnt  int64
+}
+
+// merge 合并同一包名另一来源的记录：空字段取对方的值，文本去重拼接，安装量累加
+func (d *Doc) merge(o *Doc) {
+	d.Sources = append(d.Sources, o.Sources...)
+	if d.Name == "" {
+		d.Name = o.Name
+	}
+	if d.Vendor == "" {
+		d.Vendor = o.Vendor
+	}
+	if o.Subtitle != "" && !strings.Contains(d.Subtitle, o.Subtitle) {
+		d.Subtitle = strings.TrimSpace(d.Subtitle + "\n" + o.Subtitle)
+	}
+	if o.Description != "" && !strings.Contains(d.Description, o.Description) {
+		d.Description = strings.TrimSpace(d.Description + "\n" + o.Description)
+	}
+	seen := make(map[string]bool, len(d.Tags))
+	for _, tag := range d.Tags {
+		seen[tag] = true
+	}
+	for _, tag := range o.Tags {
+		if !seen[tag] {
+			d.Tags = append(d.Tags, tag)
+			seen[tag] = true
+		}
+	}
+	d.InstallCnt += o.InstallCnt
+}
+
+// fields 返回各检索字段的文本
+func (d *Doc) fields() [fieldCount]string {
+	return [fieldCount]string{
+		fieldName:        d.Name,
+		fieldSubtitle:    d.Subtitle,
+		fieldTags:        strings.Join(d.Tags, " "),
+		fieldVendor:      d.Vendor,
+		fieldDescription: d.Description,
+	}
+}
+
+// posting 记录词元在某个Doc各字段中的出现次数
+type posting struct {
+	doc int
+	tf  [fieldCount]int
+}
+
+// Result 是一条检索结果
+type Result struct {
+	*Doc
+	Relevance float64 // BM25 相关度
+	Score     float64 // 综合安装量后的最终得分
+}
+
+// Index 是内存中的倒排索引，并发安全
+type Index struct {
+	// Popularity 为安装量的加权系数，最终得分 = 相关度 × (1 + Popularity × log10(1 + 安装量))
+	Popularity float64
+
+	sync.RWMutex
+	byID     map[string]*Doc
+	dirty    bool
+	docs     []*Doc
+	lens     [][fieldCount]int
+	avgLen   [fieldCount]float64
+	postings map[string][]posting
+}
+
+// NewIndex 创建一个空索引
+func NewIndex() *Index {
+	return &Index{Popularity: 0.1, byID: make(map[string]*Doc)}
+}
+
+// Add 向索引中添加应用，包名已存在时与已有记录合并
+func (idx *Index) Add(docs ...*Doc) {
+	idx.Lock()
+	defer idx.Unlock()
+	for _, doc := range docs {
+		if old, ok := idx.byID[doc.ID]; ok {
+			old.merge(doc)
+		} else {
+			d := *doc
+			d.Sources = append([]string(nil), doc.Sources...)
+			d.Tags = append([]string(nil), doc.Tags...)
+			idx.byID[doc.ID] = &d
+		}
+	}
+	idx.dirty = true
+}
+
+// Len 返回索引中的应用数目
+func (idx *Index) Len() int {
+	idx.RLock()
+	defer idx.RUnlock()
+	return len(idx.byID)
+}
+
+// build 重建倒排表，调用者需持有写锁
+func (idx *Index) build() {
+	idx.docs = make([]*Doc, 0, len(idx.byID))
+	for _, doc := range idx.byID {
+		idx.docs = append(idx.docs, doc)
+	}
+	sort.Slice(idx.docs, func(i, j int) bool { return idx.docs[i].ID < idx.docs[j].ID })
+
+	idx.lens = make([][fieldCount]int, len(idx.docs))
+	idx.postings = make(map[string][]posting)
+	var total [fieldCount]int
+	for i, doc := range idx.docs {
+		tfs := make(map[string]*posting)
+		for f, text := range doc.fields() {
+			tokens := Tokenize(text)
+			idx.lens[i][f] = len(tokens)
+			total[f] += len(tokens)
+			for _, token := range tokens {
+				p, ok := tfs[token]
+				if !ok {
+					p = &posting{doc: i}
+					tfs[token] = p
+				}
+				p.tf[f]++
+			}
+		}
+		for token, p := range tfs {
+			idx.postings[token] = append(idx.postings[token], *p)
+		}
+	}
+	for f := range total {
+		if len(idx.docs) > 0 {
+			idx.avgLen[f] = float64(total[f]) / float64(len(idx.docs))
+		}
+	}
+	idx.dirty = false
+}
+
+// Search 检索与query相关的应用，按得分降序返回至多limit条结果，limit<=0时返回全部
+// 各字段按BM25F加权计算相关度，再乘以安装量因子
+func (idx *Index) Search(query string, limit int) []Result {
+	idx.Lock()
+	if idx.dirty {
+		idx.build()
+	}
+	idx.Unlock()
+	idx.RLock()
+	defer idx.RUnlock()
+
+	n := float64(len(idx.docs))
+	scores := make(map[int]float64)
+	seen := make(map[string]bool)
+	for _, token := range Tokenize(query) {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+		list := idx.postings[token]
+		if len(list) == 0 {
+			continue
+		}
+		df := float64(len(list))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range list {
+			var tf float64
+			for f, cnt := range p.tf {
+				if cnt == 0 {
+					continue
+				}
+				norm := 1.0
+				if idx.avgLen[f] > 0 {
+					norm = 1 - bm25B + bm25B*float64(idx.lens[p.doc][f])/idx.avgLen[f]
+				}
+				tf += fieldWeights[f] * float64(cnt) / norm
+			}
+			scores[p.doc] += idf * tf * (bm25K1 + 1) / (tf + bm25K1)
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for i, relevance := range scores {
+		doc := idx.docs[i]
+		boost := 1 + idx.Popularity*math.Log10(1+math.Max(0, float64(doc.InstallCnt)))
+		results = append(results, Result{doc, relevance, relevance * boost})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Load 从 `android` 表读取所有来源的应用并建立索引
+func Load(db *pg.DB) (*Index, error) {
+	var rows []struct {
+		ID          string
+		Source      string
+		Name        string
+		Subtitle    string
+		Description string
+		Tags        []string `pg:",array"`
+		Vendor      string
+		InstallCnt  int64
+	}
+	if _, err := db.Query(&rows, `SELECT id, source, name, subtitle, description, tags, vendor,
+	coalesce(install_cnt, 0) AS install_cnt FROM android;`); err != nil {
+		return nil, fmt.Errorf("load apps: %s", err.Error())
+	}
+	idx := NewIndex()
+	for _, row := range rows {
+		idx.Add(&Doc{
+			ID:          row.ID,
+			Sources:     []string{row.Source},
+			Name:        row.Name,
+			Subtitle:    row.Subtitle,
+			Description: row.Description,
+			Tags:        row.Tags,
+			Vendor:      row.Vendor,
+			InstallCnt:  row.InstallCnt,
+		})
+	}
+	return idx, nil
+}
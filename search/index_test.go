@@ -0,0 +1,61 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	for _, c := range []struct {
+		text string
+		want string
+	}{
+		{"微信", "微信"},
+		{"高德地图", "高德 德地 地图"},
+		{"微信WeChat 6.5", "微信 wechat 6 5"},
+		{"王者荣耀-腾讯", "王者 者荣 荣耀 腾讯"},
+		{"图 QQ", "图 qq"},
+		{"", ""},
+	} {
+		if got := strings.Join(Tokenize(c.text), " "); got != c.want {
+			t.Errorf("Tokenize(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(
+		&Doc{ID: "com.autonavi.minimap", Sources: []string{"wdj"}, Name: "高德地图", Vendor: "高德软件", Tags: []string{"地图", "导航"}, InstallCnt: 1000},
+		&Doc{ID: "com.baidu.BaiduMap", Sources: []string{"wdj"}, Name: "百度地图", Vendor: "百度", Tags: []string{"地图"}, InstallCnt: 100000000},
+		&Doc{ID: "com.tencent.mm", Sources: []string{"wdj"}, Name: "微信", Description: "可以发送语音、视频、地图位置", InstallCnt: 100000000},
+		&Doc{ID: "com.autonavi.minimap", Sources: []string{"sjqq"}, Subtitle: "驾车导航必备", InstallCnt: 500},
+	)
+	if idx.Len() != 3 {
+		t.Fatalf("len = %d, want 3", idx.Len())
+	}
+
+	res := idx.Search("高德导航", 10)
+	if len(res) == 0 || res[0].ID != "com.autonavi.minimap" {
+		t.Fatalf("search 高德导航 = %v", res)
+	}
+	if res[0].InstallCnt != 1500 || strings.Join(res[0].Sources, ",") != "wdj,sjqq" {
+		t.Errorf("merged doc = %+v", res[0].Doc)
+	}
+
+	// 名称命中优于描述命中，同等相关度时安装量高者靠前
+	res = idx.Search("地图", 0)
+	if len(res) != 3 || res[2].ID != "com.tencent.mm" {
+		t.Fatalf("search 地图 = %v", res)
+	}
+	if res[0].ID != "com.baidu.BaiduMap" {
+		t.Errorf("popular app should rank first, got %s", res[0].ID)
+	}
+
+	if res = idx.Search("wechat", 10); len(res) != 0 {
+		t.Errorf("search wechat = %v", res)
+	}
+	if res = idx.Search("地图", 1); len(res) != 1 {
+		t.Errorf("limit is not respected: %d", len(res))
+	}
+}
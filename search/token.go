@@ -0,0 +1,57 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isCJK 判断是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// Tokenize 将文本切分为词元
+// 英文与数字按单词切分并转为小写，中文连续片段按二元组(bigram)切分
+// 单个汉字构成的片段保留为单字，例如 "微信WeChat 6.5" => [微信 wechat 6 5]
+func Tokenize(text string) (tokens []string) {
+	var word []rune
+	var han []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	flushHan := func() {
+		switch len(han) {
+		case 0:
+			return
+		case 1:
+			tokens = append(tokens, string(han))
+		default:
+			for i := 0; i+1 < len(han); i++ {
+				tokens = append(tokens, string(han[i:i+2]))
+			}
+		}
+		han = han[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return
+}
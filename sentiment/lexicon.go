@@ -0,0 +1,82 @@
+package sentiment
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// 内置词典，以空白分隔，离线可用；词典只收录评论中常见的说法，需要时直接在此增补
+
+// positiveWords 正面词
+const positiveWords = `
+好 好用 很好 不错 挺好 好玩 好看 喜欢 爱了 满意 推荐 赞 点赞 棒 很棒 完美 优秀 精彩 给力 牛 厉害
+方便 便捷 实用 简洁 清爽 流畅 顺畅 稳定 快 很快 迅速 及时 省电 省流量 贴心 人性化 良心 划算 免费
+有用 好评 五星 感谢 谢谢 支持 期待 惊喜 舒服 漂亮 精美 清晰 准确 靠谱 放心 安全 强大 丰富 有趣 上瘾
+nice good great perfect love awesome cool
+`
+
+// negativeWords 负面词
+const negativeWords = `
+差 很差 太差 垃圾 烂 坑 坑人 坑爹 骗子 骗人 失望 后悔 恶心 无语 难用 难看 难受 讨厌 烦 烦人 麻烦
+慢 很慢 卡 卡顿 卡死 闪退 崩溃 死机 黑屏 白屏 掉线 断线 延迟 错误 失败 异常 bug 毛病 问题 故障
+耗电 费电 发热 发烫 耗流量 占内存 广告 弹窗 收费 乱扣费 扣费 流氓 偷跑 强制 捆绑 诱导 骚扰 泄露
+登录不了 登不上 打不开 进不去 用不了 下载不了 安装不了 无法登录 无法打开 无法安装 无法使用 更新不了
+差评 一星 卸载 删了 不行 不能用 没用 别下 别更新 越来越差 越更新越差 变差 退步
+bad terrible crash bug ads slow
+`
+
+// complaintWords 投诉类问题词，用于归纳各版本的主要问题，均应同时收录在负面词中
+const complaintWords = `
+卡 卡顿 卡死 闪退 崩溃 死机 黑屏 白屏 掉线 断线 延迟 错误 失败 异常 bug 毛病 故障
+耗电 费电 发热 发烫 耗流量 占内存 广告 弹窗 收费 乱扣费 扣费 流氓 偷跑 强制 捆绑 诱导 骚扰 泄露
+登录不了 登不上 打不开 进不去 用不了 下载不了 安装不了 无法登录 无法打开 无法安装 无法使用 更新不了
+越来越差 越更新越差 变差 退步 crash ads slow
+`
+
+// negationWords 否定词，作用于同一分句中其后的第一个情感词
+const negationWords = `不 没 没有 别 无 未 不是 不太 不够 并不 从不 毫不 不再`
+
+// degreeWords 程度副词及其权重，作用于同一分句中其后的第一个情感词
+var degreeWords = map[string]float64{
+	"极其": 2, "极度": 2, "超级": 2, "超": 1.8, "特别": 1.8, "非常": 1.8, "太": 1.8, "十分": 1.6, "最": 1.6,
+	"很": 1.4, "挺": 1.2, "蛮": 1.2, "真": 1.3, "越来越": 1.3, "更": 1.3, "还": 1.1,
+	"有点": 0.7, "有些": 0.7, "稍微": 0.6, "略": 0.6, "一点": 0.6,
+}
+
+// 词典项类型
+const (
+	kindPositive = iota + 1
+	kindNegative
+	kindNegation
+	kindDegree
+)
+
+type entry struct {
+	kind      int
+	weight    float64
+	complaint bool
+}
+
+var (
+	lexicon   = make(map[string]entry)
+	maxWordSz = 1 // 词典中最长词的字符数
+)
+
+func addWords(words string, e entry) {
+	for _, w := range strings.Fields(words) {
+		lexicon[w] = e
+		if n := utf8.RuneCountInString(w); n > maxWordSz {
+			maxWordSz = n
+		}
+	}
+}
+
+func init() {
+	addWords(positiveWords, entry{kind: kindPositive, weight: 1})
+	addWords(negativeWords, entry{kind: kindNegative, weight: -1})
+	addWords(complaintWords, entry{kind: kindNegative, weight: -1, complaint: true})
+	addWords(negationWords, entry{kind: kindNegation})
+	for w, weight := range degreeWords {
+		addWords(w, entry{kind: kindDegree, weight: weight})
+	}
+}
@@ -0,0 +1,155 @@
+package sentiment
+
+import (
+	"sort"
+	"time"
+)
+
+import (
+	"github.com/Vonng/go-android-search/review"
+)
+
+// Phrase 是一个负面短语及其出现的评论数
+type Phrase struct {
+	Text  string
+	Count int
+}
+
+// TopComplaints 统计各版本负面评论中出现最多的n个负面短语，键为评论时的应用版本，版本未知为空串
+// 同一评论中重复的短语只计一次，出现次数相同时按短语排序
+func TopComplaints(reviews []review.Review, n int) map[string][]Phrase {
+	counts := make(map[string]map[string]int)
+	for _, r := range reviews {
+		res := Analyze(r.Content)
+		if res.Polarity() >= 0 {
+			continue
+		}
+		if counts[r.Version] == nil {
+			counts[r.Version] = make(map[string]int)
+		}
+		seen := make(map[string]bool)
+		for _, p := range res.Phrases {
+			if !seen[p] {
+				seen[p] = true
+				counts[r.Version][p]++
+			}
+		}
+	}
+
+	top := make(map[string][]Phrase, len(counts))
+	for version, phrases := range counts {
+		list := make([]Phrase, 0, len(phrases))
+		for text, cnt := range phrases {
+			list = append(list, Phrase{text, cnt})
+		}
+		sortPhrases(list)
+		if n > 0 && len(list) > n {
+			list = list[:n]
+		}
+		top[version] = list
+	}
+	return top
+}
+
+// sortPhrases 按出现次数降序、短语升序排列
+func sortPhrases(list []Phrase) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Text < list[j].Text
+	})
+}
+
+// Week 是一个应用某一周的评论情感汇总
+type Week struct {
+	Start      time.Time // 周一零点，使用评论时间所在时区
+	Count      int       // 评论数
+	Positive   int       // 正面评论数
+	Negative   int       // 负面评论数
+	Score      float64   // 平均情感得分
+	Rating     float64   // 平均评分，只计有评分的评论，无评分时为0
+	Versions   []string  // 本周评论涉及的版本，按评论数降序
+	Complaints []Phrase  // 本周出现最多的负面短语，最多5个
+}
+
+// WeekStart 返回t所在周的周一零点
+func WeekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	y, m, d := t.AddDate(0, 0, -offset).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Weekly 按周汇总评论情感，结果按时间升序，没有评论的周不出现，无评论时间的评论被忽略
+func Weekly(reviews []review.Review) []Week {
+	type acc struct {
+		week     Week
+		score    float64
+		rating   int
+		rated    int
+		versions map[string]int
+		phrases  map[string]int
+	}
+	weeks := make(map[time.Time]*acc)
+	for _, r := range reviews {
+		if r.Date.IsZero() {
+			continue
+		}
+		start := WeekStart(r.Date)
+		a := weeks[start]
+		if a == nil {
+			a = &acc{week: Week{Start: start}, versions: make(map[string]int), phrases: make(map[string]int)}
+			weeks[start] = a
+		}
+		res := Analyze(r.Content)
+		a.week.Count++
+		a.score += res.Score
+		switch res.Polarity() {
+		case 1:
+			a.week.Positive++
+		case -1:
+			a.week.Negative++
+			seen := make(map[string]bool)
+			for _, p := range res.Phrases {
+				if !seen[p] {
+					seen[p] = true
+					a.phrases[p]++
+				}
+			}
+		}
+		if r.Rating > 0 {
+			a.rating += r.Rating
+			a.rated++
+		}
+		if r.Version != "" {
+			a.versions[r.Version]++
+		}
+	}
+
+	list := make([]Week, 0, len(weeks))
+	for _, a := range weeks {
+		w := a.week
+		w.Score = a.score / float64(w.Count)
+		if a.rated > 0 {
+			w.Rating = float64(a.rating) / float64(a.rated)
+		}
+		versions := make([]Phrase, 0, len(a.versions))
+		for v, cnt := range a.versions {
+			versions = append(versions, Phrase{v, cnt})
+		}
+		sortPhrases(versions)
+		for _, v := range versions {
+			w.Versions = append(w.Versions, v.Text)
+		}
+		for text, cnt := range a.phrases {
+			w.Complaints = append(w.Complaints, Phrase{text, cnt})
+		}
+		sortPhrases(w.Complaints)
+		if len(w.Complaints) > 5 {
+			w.Complaints = w.Complaints[:5]
+		}
+		list = append(list, w)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Start.Before(list[j].Start) })
+	return list
+}
@@ -0,0 +1,112 @@
+package sentiment
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Result 是一段文本的情感分析结果
+type Result struct {
+	Score    float64  // 情感得分，范围(-1, 1)，正数为正面，0为中性或无情感词
+	Positive float64  // 正面权重之和
+	Negative float64  // 负面权重之和，为非负数
+	Phrases  []string // 负面短语，如 `闪退`、`不流畅`，按出现顺序
+}
+
+// Polarity 返回结果的倾向：1 正面，-1 负面，0 中性
+func (r Result) Polarity() int {
+	switch {
+	case r.Score > 0:
+		return 1
+	case r.Score < 0:
+		return -1
+	}
+	return 0
+}
+
+// isClauseBreak 判断是否为分句边界，否定词与程度副词只在分句内生效
+func isClauseBreak(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSpace(r) || unicode.IsSymbol(r)
+}
+
+// Analyze 使用内置词典为文本打分
+// 按最长匹配切分出词典词，否定词翻转其后第一个情感词，程度副词放大或减弱其后第一个情感词
+// 得分为 (正面-负面)/(正面+负面+1)，情感词越多越接近±1
+func Analyze(text string) (res Result) {
+	runes := []rune(strings.ToLower(text))
+	negated, negStart, degree := false, -1, 1.0
+	reset := func() { negated, negStart, degree = false, -1, 1.0 }
+
+	for i := 0; i < len(runes); {
+		if isClauseBreak(runes[i]) {
+			reset()
+			i++
+			continue
+		}
+		word, e := match(runes, i)
+		if word == 0 {
+			i++
+			continue
+		}
+		switch e.kind {
+		case kindNegation:
+			negated = !negated
+			if negStart < 0 {
+				negStart = i
+			}
+		case kindDegree:
+			degree *= e.weight
+		case kindPositive, kindNegative:
+			w := e.weight * degree
+			if negated {
+				w = -w
+			}
+			if w > 0 {
+				res.Positive += w
+			} else {
+				res.Negative -= w
+				start := i
+				if negated && e.kind == kindPositive {
+					start = negStart
+				}
+				res.Phrases = append(res.Phrases, string(runes[start:i+word]))
+			}
+			reset()
+		}
+		i += word
+	}
+
+	if sum := res.Positive + res.Negative; sum > 0 {
+		res.Score = (res.Positive - res.Negative) / (sum + 1)
+	}
+	return
+}
+
+// match 返回从位置i开始的最长词典词的字符数及词典项，未匹配时返回0
+// 英文词须整词匹配，避免 `bug` 匹配 `debugger`
+func match(runes []rune, i int) (int, entry) {
+	for n := maxWordSz; n > 0; n-- {
+		if i+n > len(runes) {
+			continue
+		}
+		e, ok := lexicon[string(runes[i:i+n])]
+		if !ok {
+			continue
+		}
+		if isASCIILetter(runes[i]) && (i > 0 && isASCIILetter(runes[i-1]) ||
+			i+n < len(runes) && isASCIILetter(runes[i+n])) {
+			continue
+		}
+		return n, e
+	}
+	return 0, entry{}
+}
+
+func isASCIILetter(r rune) bool {
+	return r < unicode.MaxASCII && unicode.IsLetter(r)
+}
+
+// IsComplaint 判断短语是否为投诉类问题词
+func IsComplaint(phrase string) bool {
+	return lexicon[phrase].complaint
+}
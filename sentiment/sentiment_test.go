@@ -0,0 +1,86 @@
+package sentiment
+
+import (
+	"time"
+	"reflect"
+	"testing"
+)
+
+import (
+	"github.com/Vonng/go-android-search/review"
+)
+
+func TestAnalyze(t *testing.T) {
+	cases := []struct {
+		text     string
+		polarity int
+		phrases  []string
+	}{
+		{"非常好用，界面简洁", 1, nil},
+		{"不错不错", 1, nil},
+		{"更新后一直闪退，还有很多广告", -1, []string{"闪退", "广告"}},
+		{"一点都不流畅，很不好用", -1, []string{"不流畅", "不好用"}},
+		{"终于没有广告了", 1, nil},
+		{"登录不了!!!", -1, []string{"登录不了"}},
+		{"Too many ads, crash again", -1, []string{"ads", "crash"}},
+		{"debugger", 0, nil},
+		{"今天天气", 0, nil},
+	}
+	for _, c := range cases {
+		res := Analyze(c.text)
+		if res.Polarity() != c.polarity || !reflect.DeepEqual(res.Phrases, c.phrases) {
+			t.Errorf("Analyze(%q) = %+v, want polarity %d phrases %v", c.text, res, c.polarity, c.phrases)
+		}
+		if res.Score <= -1 || res.Score >= 1 {
+			t.Errorf("Analyze(%q) score %f out of range", c.text, res.Score)
+		}
+	}
+
+	if Analyze("非常好").Score <= Analyze("好").Score {
+		t.Error("degree word should strengthen sentiment")
+	}
+	if !IsComplaint("闪退") || IsComplaint("好用") {
+		t.Error("IsComplaint is wrong")
+	}
+}
+
+func TestTopComplaints(t *testing.T) {
+	reviews := []review.Review{
+		{Version: "2.0", Content: "闪退闪退，又闪退"},
+		{Version: "2.0", Content: "一直闪退，耗电"},
+		{Version: "2.0", Content: "耗电太厉害了，差"},
+		{Version: "2.0", Content: "很好用"},
+		{Version: "1.9", Content: "广告太多"},
+	}
+	top := TopComplaints(reviews, 2)
+	want := []Phrase{{"耗电", 2}, {"闪退", 2}}
+	if !reflect.DeepEqual(top["2.0"], want) {
+		t.Errorf("2.0 complaints = %v, want %v", top["2.0"], want)
+	}
+	if len(top["1.9"]) != 1 || top["1.9"][0].Text != "广告" {
+		t.Errorf("1.9 complaints = %v", top["1.9"])
+	}
+}
+
+func TestWeekly(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2017, 7, d, 12, 0, 0, 0, time.UTC) }
+	reviews := []review.Review{
+		{Date: day(31), Version: "2.0", Rating: 1, Content: "更新后闪退"}, // 周一
+		{Date: day(30), Version: "1.9", Rating: 5, Content: "好用"},    // 周日
+		{Date: day(24), Version: "1.9", Rating: 4, Content: "不错"},
+		{Date: day(26), Version: "1.9", Content: "一般"},
+		{Content: "没有时间"},
+	}
+	weeks := Weekly(reviews)
+	if len(weeks) != 2 {
+		t.Fatalf("weeks = %+v", weeks)
+	}
+	w := weeks[0]
+	if !w.Start.Equal(day(24).Add(-12*time.Hour)) || w.Count != 3 || w.Positive != 2 || w.Rating != 4.5 {
+		t.Errorf("first week = %+v", w)
+	}
+	w = weeks[1]
+	if w.Negative != 1 || w.Score >= 0 || w.Versions[0] != "2.0" || w.Complaints[0].Text != "闪退" {
+		t.Errorf("second week = %+v", w)
+	}
+}
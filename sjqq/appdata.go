@@ -0,0 +1,124 @@
+package sjqq
+
+import (
+	"time"
+	"regexp"
+	"encoding/json"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vonng/go-android-search/jsobj"
+)
+
+// AppDetailData 为详情页底部脚本中的 appDetailData 对象，值多以字符串形式给出
+//
+//	var appDetailData = {
+//		apkName : "com.tencent.mm",
+//		apkCode : "1080",
+//		appId : "10910",
+//		downTimes:"4356071202",
+//		...
+//	}
+type AppDetailData struct {
+	OrGame        string  `js:"orgame"`         // 应用或游戏标记
+	ApkName       string  `js:"apkName"`        // 包名
+	ApkCode       int64   `js:"apkCode"`        // 版本号 versionCode
+	AppID         int64   `js:"appId"`          // 应用宝分配的应用ID
+	AppName       string  `js:"appName"`        // 名称
+	IconURL       string  `js:"iconUrl"`        // 图标
+	DownURL       string  `js:"downUrl"`        // 下载链接，fsname 参数中带有版本
+	DownTimes     int64   `js:"downTimes"`      // 下载次数
+	AppScore      float64 `js:"appScore"`       // 评分，0~5，多为空
+	AverageRating float64 `js:"averageRating"`  // 平均评分，0~5
+	VersionName   string  `js:"versionName"`    // 版本
+	CategoryID    int64   `js:"categoryId"`     // 分类ID
+	PublishTime   int64   `js:"apkPublishTime"` // 发布时间，unix 时间戳
+	FileSize      int64   `js:"fileSize"`       // 安装包大小，字节
+	TipsUpDown    bool    `js:"tipsUpDown"`
+}
+
+// pFsName 从下载链接的 fsname 中取版本，如 `fsname=com.tencent.mm_6.5.10_1080.apk`
+var pFsName = regexp.MustCompile(`fsname=[^&_]+_([^&_]+)_\d+\.apk`)
+
+// parseAppDetailData 在页面的脚本中找到 appDetailData 并解析，对象缺失或格式有误时返回 nil 与错误
+// 个别值无法转换时跳过这些字段，返回其余字段与 jsobj.FieldErrors；appId、apkCode 是否缺失由调用方检查
+func parseAppDetailData(doc *goquery.Document) (*AppDetailData, error) {
+	err := jsobj.ErrNotFound
+	data := new(AppDetailData)
+	doc.Find("script").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		err = jsobj.Unmarshal(s.Text(), "appDetailData", data)
+		return err == jsobj.ErrNotFound
+	})
+	if _, ok := err.(jsobj.FieldErrors); err != nil && !ok {
+		return nil, err
+	}
+	return data, err
+}
+
+// Version 返回版本，脚本中没有 versionName 时从下载链接中获取
+func (d *AppDetailData) Version() string {
+	if d.VersionName != "" {
+		return d.VersionName
+	}
+	if m := pFsName.FindStringSubmatch(d.DownURL); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Stars 返回五星平均分，没有 averageRating 时取 appScore
+func (d *AppDetailData) Stars() float64 {
+	if d.AverageRating > 0 {
+		return d.AverageRating
+	}
+	return d.AppScore
+}
+
+// Rating 返回百分制评分，与页面上的 `4.3分` 换算方式相同
+func (d *AppDetailData) Rating() int64 {
+	return int64(float32(d.Stars()) * 20)
+}
+
+// apply 以脚本中的数据填充应用，脚本中的值比页面元素可靠，非零时覆盖页面上解析到的值
+// 页面上的版本与名称更完整，仅在为空时填充；分类ID记录在 Extra 中
+func (d *AppDetailData) apply(app *App) {
+	if app.ID == "" {
+		app.ID = d.ApkName
+	}
+	if app.Name == "" {
+		app.Name = d.AppName
+	}
+	if app.Version == "" {
+		app.Version = d.Version()
+	}
+	if d.AppID > 0 {
+		app.AppID = d.AppID
+	}
+	if d.ApkCode > 0 {
+		app.ApkCode = d.ApkCode
+	}
+	if d.IconURL != "" {
+		app.Icon = d.IconURL
+	}
+	if d.DownURL != "" {
+		app.Link = d.DownURL
+	}
+	if d.DownTimes > 0 {
+		app.InstallCnt = d.DownTimes
+	}
+	if rating := d.Rating(); rating > 0 {
+		app.Rating = rating
+		app.RatingValue = d.Stars()
+	}
+	if d.FileSize > 0 {
+		app.Size = d.FileSize
+	}
+	if d.PublishTime > 0 {
+		app.ReleaseTime = time.Unix(d.PublishTime, 0)
+	}
+	if d.CategoryID > 0 {
+		extra, _ := json.Marshal(map[string]int64{"category_id": d.CategoryID})
+		app.Extra = string(extra)
+	}
+}
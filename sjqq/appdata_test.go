@@ -0,0 +1,62 @@
+package sjqq
+
+import (
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vonng/go-android-search/jsobj"
+)
+
+func TestParseAppDetailData(t *testing.T) {
+	for _, filename := range ReadAllFilename("sample") {
+		doc, err := buildDocumentFromFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := parseAppDetailData(doc)
+		if err != nil {
+			t.Errorf("%s: %s", filename, err)
+			continue
+		}
+		if !strings.Contains(filename, data.ApkName) || data.AppID == 0 || data.ApkCode == 0 || data.DownTimes == 0 {
+			t.Errorf("%s: %+v", filename, data)
+		}
+		if data.Version() == "" {
+			t.Errorf("%s: version not found in %s", filename, data.DownURL)
+		}
+	}
+
+	page := func(script string) *goquery.Document {
+		doc, _ := goquery.NewDocumentFromReader(strings.NewReader("<html><script>" + script + "</script></html>"))
+		return doc
+	}
+	data, err := parseAppDetailData(page(`var appDetailData = {apkName: "a.b", apkCode: "12", appId: "34",
+		averageRating: "4.3", categoryId: "122", apkPublishTime: "1501491243", fileSize: "1024"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &App{Rating: 10, Size: 1}
+	data.apply(app)
+	if app.ID != "a.b" || app.AppID != 34 || app.ApkCode != 12 || app.Rating != 86 || app.RatingValue != 4.3 || app.Size != 1024 ||
+		app.ReleaseTime.Unix() != 1501491243 || app.Extra != `{"category_id":122}` {
+		t.Errorf("apply: %+v", app)
+	}
+
+	for _, script := range []string{
+		`var other = {}`,
+		`var appDetailData = {apkCode: "12", appId: "34"`,
+	} {
+		if data, err := parseAppDetailData(page(script)); err == nil || data != nil {
+			t.Errorf("%s: should fail", script)
+		}
+	}
+
+	// 个别值有误时跳过该值，其余字段照常返回
+	data, err = parseAppDetailData(page(`var appDetailData = {apkName: "a.b", apkCode: "12", appId: "34", fileSize: "1 MB"}`))
+	if _, ok := err.(jsobj.FieldErrors); !ok || data == nil || data.AppID != 34 || data.ApkCode != 12 || data.FileSize != 0 {
+		t.Errorf("bad field: %+v, %v", data, err)
+	}
+}
@@ -0,0 +1,23 @@
+package sjqq
+
+import (
+	"github.com/Vonng/go-android-search/fill"
+)
+
+// unmonitoredFields 不参与填充率统计的字段：固定值、应用宝无或规则未抽取的字段与由评论接口补充的字段
+var unmonitoredFields = []string{
+	"source", "url", "tags", "categories", "price", "system", "platform", "appkey",
+	"subtitle", "commentary", "commentary_html", "release_note", "release_note_html",
+	"reviews", "news", "extra", "comment_cnt", "rating_cnt", "unmapped_perms", "crawled_time",
+}
+
+// Filled 返回解析后各字段是否被正确填充，用于发现页面改版导致的选择器失效
+// 除非零值外，版本号还须符合格式，选择器取错位置时视为未填充
+func (app *App) Filled() map[string]bool {
+	filled := fill.Fields(app)
+	for _, field := range unmonitoredFields {
+		delete(filled, field)
+	}
+	filled["version"] = fill.IsVersion(app.Version)
+	return filled
+}
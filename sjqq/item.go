@@ -4,16 +4,16 @@ import (
 	"os"
 	"fmt"
 	"time"
-	"regexp"
 	"errors"
-	"strconv"
-	"strings"
 	"text/template"
 )
 
 import (
-	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/Vonng/go-android-search/norm"
+	"github.com/Vonng/go-android-search/perm"
+	"github.com/Vonng/go-android-search/review"
 )
 
 const (
@@ -34,8 +34,11 @@ const (
 ┃ System      ┆ {{.System      }}
 ┃ Platform    ┆ {{.Platform    }}
 ┃ Permissions ┆ {{.Permissions }}
+┃ PermIDs     ┆ {{.PermissionIDs }}
+┃ Unmapped    ┆ {{.UnmappedPerms }}
 ┃ Size        ┆ {{.Size        }}
 ┃ Rating      ┆ {{.Rating      }}
+┃ RatingScore ┆ {{.RatingScore }} ({{.RatingValue }} {{.RatingScale }}, {{.RatingCnt }})
 ┃ InstallCnt  ┆ {{.InstallCnt  }}
 ┃ CommentCnt  ┆ {{.CommentCnt  }}
 ┃ Appkey      ┆ {{.Appkey      }}
@@ -64,14 +67,6 @@ const (
 
 var ErrParse = errors.New("parse error")
 var appTmpl, _ = template.New("wdj.app").Parse(appTemplate)
-// 用于解析底层Script的正则表达式
-var (
-	pApkCode  = regexp.MustCompile(`apkCode\s*:\s*"(\d+)",`)
-	pAppId    = regexp.MustCompile(`appId\s*:\s*"(\d+)",`)
-	pDownTime = regexp.MustCompile(`downTimes\s*:\s*"(\d+)",`)
-	pIconUrl  = regexp.MustCompile(`iconUrl\s*:\s*"(\S+)",`)
-	pDownUrl  = regexp.MustCompile(`downUrl\s*:\s*"(\S+)",`)
-)
 
 func AppPageURL(id string) string {
 	return AppPagePrefix + id
@@ -79,41 +74,56 @@ func AppPageURL(id string) string {
 
 // App 包括手机QQ应用页中能获取的信息
 type App struct {
-	Source      string                  // 数据来源，固定为应用宝`sjqq`
-	ID          string   `sql:",pk"`    // 标识，实质上是PkgName id
-	Name        string                  // 名称 name
-	URL         string                  // 页面 url
-	Icon        string                  // 图标 icon
-	Link        string                  // 下载 link
-	Version     string                  // 版本 version
-	Vendor      string                  // 厂商 vendor
-	Genre       string                  // 分类 genre
-	Tags        []string  `pg:",array"` // 标签，应用宝无 tags
-	Categories  []string  `pg:",array"` // 类目，应用宝无 categories
-	Price       int64                   // 价格，应用宝无 price 留空
-	System      string                  // 系统要求，应用宝无
-	Platform    []string  `pg:",array"` // 平台，应用宝无
-	Permissions []string  `pg:",array"` // 所需权限，应用宝较为详细 permissions
-	Size        int64                   // 大小 size
-	Rating      int64                   // 评分 rating
-	InstallCnt  int64                   // 安装数 install_cnt
-	CommentCnt  int64                   // 评论数 comment_cnt
-	Appkey      string                  // 友盟分配的Appkey，留空
-	AppID       int64                   // 应用宝分配的应用ID app_id
-	ApkCode     int64                   // 应用宝平台分配的Apk代码 apk_code
-	Subtitle    string                  // 副标题，应用宝无 subtitle
-	Commentary  string                  // 编辑评论，应用宝无 commentary
-	Description string                  // 应用描述，带有换行符 description
-	Reviews     string                  // 客户评论，应用宝暂无
-	News        string                  // 新闻技巧与攻略，应用宝暂无
-	Extra       string                  // 额外信息，目前置空。
-	Screenshots []string  `pg:",array"` // 截图列表 screenshots
-	RelatedApps []string  `pg:",array"` // 推荐的相关应用 related_apps
-	SiblingApps []string  `pg:",array"` // 同一开发者的其他应用 sibling_apps
-	ReleaseNote string                  // 最近更新日志,带有换行符 release_note
-	ReleaseTime time.Time               // 最近更新时间 release_time
-	CrawledTime time.Time               // 最近爬取时间 crawl_time
-	tableName   struct{} `sql:"sjqq"`
+	Source          string            // 数据来源，固定为应用宝`sjqq`
+	ID              string            `sql:",pk"` // 标识，实质上是PkgName id
+	Name            string            // 名称 name
+	URL             string            // 页面 url
+	Icon            string            // 图标 icon
+	Link            string            // 下载 link
+	Version         string            // 版本 version
+	Vendor          string            // 厂商 vendor
+	Genre           string            // 分类 genre
+	Tags            []string          `pg:",array"` // 标签，应用宝无 tags
+	Categories      []string          `pg:",array"` // 类目，应用宝无 categories
+	Price           int64             // 价格，单位为分，应用宝无 price
+	System          string            // 系统要求，应用宝无
+	Platform        []string          `pg:",array"` // 平台，应用宝无
+	Permissions     []string          `pg:",array"` // 所需权限，应用宝较为详细 permissions
+	PermissionIDs   []string          `pg:",array"` // 映射后的权限名，如 android.permission.READ_SMS permission_ids
+	UnmappedPerms   []string          `pg:",array"` // 无法映射的权限描述，供核对后增补映射表 unmapped_perms
+	Size            int64             // 大小 size
+	Rating          int64             // 评分 rating
+	RatingValue     float64           // 原始评分，即五星平均分 1~5 rating_value
+	RatingScale     string            // 原始评分的量纲，固定为 stars rating_scale
+	RatingScore     float64           // 归一化到 [0,1] 的评分，可跨来源比较 rating_score
+	RatingCnt       int64             // 评分人数，由评论接口返回的总数填充 rating_cnt
+	InstallCnt      int64             // 安装数 install_cnt
+	CommentCnt      int64             // 评论数 comment_cnt
+	Appkey          string            // 友盟分配的Appkey，留空
+	AppID           int64             // 应用宝分配的应用ID app_id
+	ApkCode         int64             // 应用宝平台分配的Apk代码 apk_code
+	Subtitle        string            // 副标题，应用宝无 subtitle
+	Commentary      string            // 编辑评论，应用宝无 commentary
+	Description     string            // 应用描述，带有换行符 description
+	Reviews         []review.Review   // 客户评论，详情页无，应用宝评论见 FetchReviews 与 review 表
+	News            []review.NewsItem // 新闻技巧与攻略，应用宝暂无
+	Extra           string            // 额外信息，目前置空。
+	Screenshots     []string          `pg:",array"` // 截图列表 screenshots
+	RelatedApps     []string          `pg:",array"` // 推荐的相关应用 related_apps
+	SiblingApps     []string          `pg:",array"` // 同一开发者的其他应用 sibling_apps
+	ReleaseNote     string            // 最近更新日志,带有换行符 release_note
+	DescriptionHTML string            // 应用描述，清理后的HTML description_html
+	CommentaryHTML  string            // 编辑评论，清理后的HTML，应用宝无 commentary_html
+	ReleaseNoteHTML string            // 最近更新日志，清理后的HTML release_note_html
+	ReleaseTime     time.Time         // 最近更新时间 release_time
+	CrawledTime     time.Time         // 最近爬取时间 crawl_time
+	tableName       struct{}          `sql:"sjqq"`
+	scriptErr       error             // 解析 appDetailData 的错误，不入库
+}
+
+// ScriptErr 返回解析页面脚本中 appDetailData 的错误，为 jsobj.FieldErrors 时其余字段已正常使用
+func (app *App) ScriptErr() error {
+	return app.scriptErr
 }
 
 // App_Valid
@@ -129,121 +139,42 @@ func (app *App) Print() {
 	return
 }
 
-// App_Parse 核心解析逻辑
+// App_Parse 按抽取规则解析详情页，规则见 defaultSpec 与 SetSpec
 func (app *App) Parse(doc *goquery.Document) error {
-	// app.ID 包名，必需存在
-	app.ID = getAttr(doc.Find("a.det-down-btn"), "apk")
-
-	// app.URL
-	app.URL = AppPageURL(app.ID)
-
-	// app.Name
-	app.Name = getText(doc.Find("div.det-name-int"))
-
-	if app.ID == "" || app.Name == "" {
-		return ErrParse
-	}
-
-	// quick selector
-	oi := doc.Find("div.det-othinfo-container")
-
-	// app.Icon
-	app.Icon = getAttr(doc.Find("div.app-icon img"), "src")
-
-	// app.Link
-	app.Link = getAttr(doc.Find("a.det-ins-btn"), "ex_url")
-
-	// app.Size
-	if size := getText(doc.Find("div.det-size")); size != "" {
-		app.Size, _ = bytesToInt(size)
-	}
-
-	// app.Rank
-	if rank := getText(doc.Find("div.com-blue-star-num")); rank != "" {
-		if num, err := strconv.ParseFloat(strings.TrimRight(rank, "分"), 32); err == nil {
-			app.Rating = int64(num * 20)
-		}
+	// 选择器与正则定义在抽取规则中，id 与 name 为必需字段
+	spec := Spec()
+	extractErr := spec.Extract(doc, app)
+
+	// app.AppID app.ApkCode 等来自底部脚本中的 appDetailData，个别值有误时跳过该值，
+	// 错误记录在 ScriptErr 中，页面元素取到的值保留
+	data, err := parseAppDetailData(doc)
+	app.scriptErr = err
+	if data != nil {
+		data.apply(app)
 	}
 
-	// app.AppID from script json
-	// app.ApkCode from script json
-
-	// app.InstallCnt
-	if install := getText(doc.Find("div.det-ins-num")); install != "" {
-		app.InstallCnt, _ = parseZhNumber(strings.TrimRight(install, "下载"))
+	// 应用宝没有 schema.org 标注，取到脚本数据后再检查必需字段
+	if extractErr != nil && spec.Check(app) != nil {
+		return ErrParse
 	}
-
-	// app.CommentCnt TBD
-
-	// app.Genre
-	app.Genre = getText(doc.Find("#J_DetCate"))
-
-	// app.Vendor
-	app.Vendor = getText(oi.Find("div:nth-of-type(6)"))
-
-	// app.Version
-	if version := getText(oi.Find("div.det-othinfo-data:nth-of-type(2)")); version != "" {
-		app.Version = strings.TrimLeft(version, "V")
+	if app.AppID == 0 || app.ApkCode == 0 {
+		return ErrParse
 	}
 
-	// app.Description
-	app.Description = getRichText(doc.Find("div.det-app-data-info:first-of-type"))
-
-	// app.Permissions
-	app.Permissions = getTextList(doc.Find("ul.det-othinfo-plist div.r"))
-
-	// app.Screenshots
-	app.Screenshots = getAttrList(doc.Find("div.pic-img-box img"), "data-src")
-
-	// app.RelatedApps
-	app.RelatedApps = getAttrList(doc.Find("li.det-about-app-box a.com-install-btn"), "apk")
+	// app.URL
+	app.URL = AppPageURL(app.ID)
 
-	// app.SiblingApps
-	app.SiblingApps = getAttrList(doc.Find("li.det-samedeve-app-box a.com-install-btn"), "apk")
+	// app.PermissionIDs, app.UnmappedPerms
+	app.PermissionIDs, app.UnmappedPerms = perm.Resolve(app.Permissions)
 
-	// app.ReleaseTime
-	if rt := getAttr(doc.Find("#J_ApkPublishTime"), "data-apkpublishtime"); rt != "" {
-		if num, err := strconv.ParseInt(rt, 10, 64); err == nil {
-			app.ReleaseTime = time.Unix(num, 0)
-		}
+	// app.RatingScale, app.RatingScore
+	// 应用宝的评分为五星平均分
+	if app.RatingValue > 0 {
+		app.RatingScale = norm.ScaleStars
+		app.RatingScore = norm.Score(app.RatingValue, app.RatingScale)
 	}
 
-	// 解析最后的script元素: AppId ApkCode IconURL DownURL InstallCnt
-	if script := getText(doc.Find("script:last-of-type")); script != "" {
-		if start, stop :=
-			strings.IndexByte(script, '{'),
-			strings.LastIndexByte(script, '}');
-			start != -1 && stop != -1 {
-			script = script[start:stop+1]
-
-			// APK Code
-			if res := pApkCode.FindStringSubmatch(script); res != nil && len(res) > 1 {
-				if i, err := strconv.Atoi(res[1]); err == nil {
-					app.ApkCode = int64(i)
-				}
-			}
-			if res := pAppId.FindStringSubmatch(script); res != nil && len(res) > 1 {
-				if i, err := strconv.Atoi(res[1]); err == nil {
-					app.AppID = int64(i)
-				}
-			}
-			if res := pIconUrl.FindStringSubmatch(script); res != nil && len(res) > 1 {
-				if iconUrl := res[1]; iconUrl != "" {
-					app.Icon = iconUrl
-				}
-			}
-			if res := pDownUrl.FindStringSubmatch(script); res != nil && len(res) > 1 {
-				if downUrl := res[1]; downUrl != "" {
-					app.Link = downUrl
-				}
-			}
-			if res := pDownTime.FindStringSubmatch(script); res != nil && len(res) > 1 {
-				if i, err := strconv.Atoi(res[1]); err == nil {
-					app.InstallCnt = int64(i)
-				}
-			}
-		}
-	}
+	// app.CommentCnt, app.RatingCnt 详情页上没有，由评论接口返回的总数填充，见 FetchReviews
 
 	// app.CrawledTime
 	app.CrawledTime = time.Now()
@@ -254,7 +185,7 @@ func (app *App) Parse(doc *goquery.Document) error {
 	return nil
 }
 
-func (app *App) Save(db *pg.DB) error {
+func (app *App) Save(db orm.DB) error {
 	_, err := db.Model(app).
 		OnConflict("(id) DO UPDATE").
 		Set("source= ?source").
@@ -271,8 +202,14 @@ func (app *App) Save(db *pg.DB) error {
 		Set("system= ?system").
 		Set("platform= ?platform").
 		Set("permissions= ?permissions").
+		Set("permission_ids= ?permission_ids").
+		Set("unmapped_perms= ?unmapped_perms").
 		Set("size= ?size").
 		Set("rating= ?rating").
+		Set("rating_value= ?rating_value").
+		Set("rating_scale= ?rating_scale").
+		Set("rating_score= ?rating_score").
+		Set("rating_cnt= ?rating_cnt").
 		Set("install_cnt= ?install_cnt").
 		Set("comment_cnt= ?comment_cnt").
 		Set("appkey= ?appkey").
@@ -288,6 +225,9 @@ func (app *App) Save(db *pg.DB) error {
 		Set("related_apps= ?related_apps").
 		Set("sibling_apps= ?sibling_apps").
 		Set("release_note= ?release_note").
+		Set("description_html= ?description_html").
+		Set("commentary_html= ?commentary_html").
+		Set("release_note_html= ?release_note_html").
 		Set("release_time= ?release_time").
 		Set("crawled_time= ?crawled_time").
 		Insert()
@@ -1,8 +1,12 @@
 package sjqq
 
 import (
+	"strings"
 	"testing"
+	"io/ioutil"
+	"path/filepath"
 	"github.com/go-pg/pg"
+	"github.com/PuerkitoBio/goquery"
 )
 
 func TestApp_Parse(t *testing.T) {
@@ -41,3 +45,53 @@ func TestApp_Save(t *testing.T) {
 		}
 	}
 }
+
+func TestParseFile(t *testing.T) {
+	for _, filename := range ReadAllFilename("sample") {
+		app, err := ParseFile(filename)
+		if err != nil {
+			t.Errorf("%s: %s", filename, err)
+			continue
+		}
+		if want := strings.TrimSuffix(filepath.Base(filename), ".html"); app.ID != want {
+			t.Errorf("%s: id = %s, want %s", filename, app.ID, want)
+		}
+		// 参与统计的字段在样例页面上均应填充，规则不抽取的字段须列入 unmonitoredFields，否则填充率恒为0
+		for field, ok := range app.Filled() {
+			if !ok {
+				t.Errorf("%s: %s is not filled", filename, field)
+			}
+		}
+		if app.RatingScale != "stars" || app.RatingScore <= 0 || app.RatingScore > 1 {
+			t.Errorf("%s: rating = %v %s %v %d", filename, app.RatingValue, app.RatingScale, app.RatingScore, app.RatingCnt)
+		}
+		if len(app.PermissionIDs) == 0 {
+			t.Errorf("%s: permissions %v, unmapped %v", filename, app.PermissionIDs, app.UnmappedPerms)
+		}
+	}
+	if _, err := ParseFile("sample/not-exist.html"); err == nil {
+		t.Error("missing file should fail")
+	}
+}
+
+func TestParseBadScript(t *testing.T) {
+	page, err := ioutil.ReadFile("sample/com.tencent.mm.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse := func(old, repl string) (*App, error) {
+		doc, _ := goquery.NewDocumentFromReader(strings.NewReader(strings.Replace(string(page), old, repl, 1)))
+		app := new(App)
+		return app, app.Parse(doc)
+	}
+
+	// 个别值有误时跳过该值，页面元素取到的值保留
+	app, err := parse(`downTimes:"4356071202"`, `downTimes:"43亿"`)
+	if err != nil || app.AppID != 10910 || app.InstallCnt == 0 || app.ScriptErr() == nil {
+		t.Errorf("bad downTimes: %v, app_id %d, install_cnt %d, script err %v", err, app.AppID, app.InstallCnt, app.ScriptErr())
+	}
+	// appId 缺失时解析失败
+	if _, err = parse(`appId : "10910"`, `appId : ""`); err != ErrParse {
+		t.Errorf("missing appId: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package sjqq
+
+import (
+	"fmt"
+	"errors"
+	"strings"
+	"net/http"
+	"io/ioutil"
+	"encoding/json"
+)
+
+const (
+	listPageSize = 20
+	// 分类列表接口，返回JSON，pageContext 为已获取的应用数目
+	categoryListURL = "http://sj.qq.com/myapp/cate/appList.htm?orgame=%s&categoryId=%s&pageSize=%d&pageContext=%d"
+)
+
+// Charts 为应用宝榜单名称与接口地址模板（参数为 pageSize 与 pageContext），接口须与分类列表返回相同格式的JSON
+// 榜单接口尚未在抓取的页面中确认，暂不内置，确认后在此增补
+var Charts = map[string]string{}
+
+// errListEnd 表示列表页不存在，即已越过列表的最后一页
+var errListEnd = errors.New("end of list")
+
+// MaxListPages 为分类与榜单最多抓取的页数
+var MaxListPages = 10
+
+// ListItem 是分类列表或榜单中的一个应用
+type ListItem struct {
+	Pkg      string // 包名
+	Page     int    // 所在页码，从1开始
+	Position int    // 页内位置，从1开始
+	Rank     int    // 在整个列表中的排名，从1开始
+}
+
+// CategoryURL 生成分类列表接口URL
+// 分类ID形如 `106`(应用) 或 `2_147`(`orgame_categoryId`，orgame 1为应用，2为游戏)
+func CategoryURL(id string, page int) string {
+	orgame, cate := "1", id
+	if i := strings.IndexByte(id, '_'); i > 0 {
+		orgame, cate = id[:i], id[i+1:]
+	}
+	return fmt.Sprintf(categoryListURL, orgame, cate, listPageSize, (page-1)*listPageSize)
+}
+
+// Category 抓取分类列表，按列表顺序返回其中的应用
+// 第一页之后的页面失败时，返回已获取的应用及错误
+func Category(id string) ([]ListItem, error) {
+	return crawlList(func(page int) string { return CategoryURL(id, page) }, MaxListPages)
+}
+
+// Chart 抓取榜单，name 为 Charts 中的榜单名称
+func Chart(name string) ([]ListItem, error) {
+	tmpl, ok := Charts[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown chart %q", name)
+	}
+	return crawlList(func(page int) string {
+		return fmt.Sprintf(tmpl, listPageSize, (page-1)*listPageSize)
+	}, MaxListPages)
+}
+
+// listResponse 为列表接口返回的JSON
+type listResponse struct {
+	Success bool `json:"success"`
+	Obj     []struct {
+		PkgName string `json:"pkgName"`
+		AppName string `json:"appName"`
+	} `json:"obj"`
+}
+
+// parseListResponse 解析列表接口返回的应用包名
+func parseListResponse(body []byte) ([]string, error) {
+	var res listResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, ErrParse
+	}
+	if !res.Success {
+		return nil, errors.New("list api returns failure")
+	}
+	apks := make([]string, 0, len(res.Obj))
+	for _, app := range res.Obj {
+		if app.PkgName != "" {
+			apks = append(apks, app.PkgName)
+		}
+	}
+	return apks, nil
+}
+
+// fetchListPage 获取列表接口的一页，页面不存在时返回 errListEnd
+func fetchListPage(pageURL string) ([]string, error) {
+	res, err := Client.Get(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, errListEnd
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status %d", res.StatusCode)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseListResponse(body)
+}
+
+// crawlList 逐页抓取列表，直到页面不存在、没有新应用或达到最大页数
+func crawlList(pageURL func(page int) string, maxPages int) (items []ListItem, err error) {
+	seen := make(map[string]bool)
+	for page := 1; page <= maxPages; page++ {
+		apks, err := fetchListPage(pageURL(page))
+		if err == errListEnd && page > 1 {
+			break
+		}
+		if err != nil {
+			if page == 1 {
+				return nil, err
+			}
+			return items, fmt.Errorf("page %d %s: %s", page, pageURL(page), err)
+		}
+		added := 0
+		for i, apk := range apks {
+			if seen[apk] {
+				continue
+			}
+			seen[apk] = true
+			added++
+			items = append(items, ListItem{apk, page, i + 1, len(items) + 1})
+		}
+		if added == 0 {
+			break
+		}
+	}
+	return items, nil
+}
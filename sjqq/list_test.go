@@ -0,0 +1,69 @@
+package sjqq
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+func TestCategoryURL(t *testing.T) {
+	if u := CategoryURL("106", 1); u != "http://sj.qq.com/myapp/cate/appList.htm?orgame=1&categoryId=106&pageSize=20&pageContext=0" {
+		t.Errorf("category url = %s", u)
+	}
+	if u := CategoryURL("2_147", 3); u != "http://sj.qq.com/myapp/cate/appList.htm?orgame=2&categoryId=147&pageSize=20&pageContext=40" {
+		t.Errorf("category url = %s", u)
+	}
+}
+
+func TestCrawlList(t *testing.T) {
+	pages := map[string]string{
+		"1": `{"success":true,"obj":[{"pkgName":"com.tencent.mm","appName":"微信"},{"pkgName":"com.tencent.mobileqq"}]}`,
+		"2": `{"success":true,"obj":[{"pkgName":"com.tencent.mobileqq"},{"pkgName":"com.tencent.tmgp.sgame"}]}`,
+		"3": `{"success":true,"obj":[]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		} else if body, ok := pages[r.URL.Query().Get("page")]; ok {
+			fmt.Fprint(w, body)
+		} else {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	items, err := crawlList(func(page int) string { return fmt.Sprintf("%s?page=%d", server.URL, page) }, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, item := range items {
+		got = append(got, fmt.Sprintf("%s:%d:%d:%d", item.Pkg, item.Page, item.Position, item.Rank))
+	}
+	if strings.Join(got, " ") != "com.tencent.mm:1:1:1 com.tencent.mobileqq:1:2:2 com.tencent.tmgp.sgame:2:2:3" {
+		t.Errorf("items = %v", got)
+	}
+
+	// 越过最后一页时接口返回404，列表完整结束
+	delete(pages, "3")
+	items, err = crawlList(func(page int) string {
+		if page == 3 {
+			return server.URL + "/missing"
+		}
+		return fmt.Sprintf("%s?page=%d", server.URL, page)
+	}, 10)
+	if err != nil || len(items) != 3 {
+		t.Errorf("list ends at missing page = %v %v", items, err)
+	}
+	if _, err = crawlList(func(int) string { return server.URL + "/missing" }, 10); err == nil {
+		t.Error("missing first page should fail")
+	}
+
+	pages["2"] = `<html>`
+	items, err = crawlList(func(page int) string { return fmt.Sprintf("%s?page=%d", server.URL, page) }, 10)
+	if err == nil || len(items) != 2 {
+		t.Errorf("partial = %v %v", items, err)
+	}
+}
@@ -0,0 +1,89 @@
+package sjqq
+
+import (
+	"fmt"
+	"time"
+	"net/url"
+	"strconv"
+	"net/http"
+	"io/ioutil"
+	"encoding/json"
+)
+
+import (
+	"github.com/Vonng/go-android-search/review"
+)
+
+// ReviewListURL 为评论列表接口，contextData 为上一页返回的游标
+var ReviewListURL = "http://sj.qq.com/myapp/app/comment.htm?apkName=%s&contextData=%s"
+
+// reviewResponse 为评论接口返回的JSON
+type reviewResponse struct {
+	Success bool `json:"success"`
+	Obj     struct {
+		HasNext     int    `json:"hasNext"`
+		Total       int64  `json:"total"`
+		ContextData string `json:"contextData"`
+		Comments    []struct {
+			ID          json.Number `json:"id"`
+			NickName    string      `json:"nickName"`
+			Score       int         `json:"score"`
+			CreatedTime int64       `json:"createdTime"` // unix 时间戳
+			Content     string      `json:"content"`
+			VersionName string      `json:"versionName"`
+			PraiseNum   int64       `json:"praiseNum"`
+		} `json:"commentDetails"`
+	} `json:"obj"`
+}
+
+// ReviewURL 生成评论接口URL，cursor 为空时为第一页
+func ReviewURL(id, cursor string) string {
+	return fmt.Sprintf(ReviewListURL, url.QueryEscape(id), url.QueryEscape(cursor))
+}
+
+// FetchReviews 获取应用的一页评论，可作为 review.Fetcher，返回的 Total 为评论总数
+func FetchReviews(id, cursor string) (*review.Page, error) {
+	res, err := Client.Get(ReviewURL(id, cursor))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http status %d", res.StatusCode)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseReviewResponse(body, id)
+}
+
+// parseReviewResponse 解析评论接口返回的JSON
+func parseReviewResponse(body []byte, id string) (*review.Page, error) {
+	var res reviewResponse
+	if err := json.Unmarshal(body, &res); err != nil || !res.Success {
+		return nil, ErrParse
+	}
+	page := &review.Page{Total: res.Obj.Total}
+	if res.Obj.HasNext == 1 {
+		page.Next = res.Obj.ContextData
+	}
+	for _, c := range res.Obj.Comments {
+		r := review.Review{
+			Source:  "sjqq",
+			AppID:   id,
+			ID:      c.ID.String(),
+			User:    c.NickName,
+			Rating:  c.Score,
+			Date:    time.Unix(c.CreatedTime, 0),
+			Content: c.Content,
+			Version: c.VersionName,
+			Likes:   c.PraiseNum,
+		}
+		if r.ID == "" {
+			r.ID = review.HashID(r.User, strconv.FormatInt(c.CreatedTime, 10), r.Content)
+		}
+		page.Reviews = append(page.Reviews, r)
+	}
+	return page, nil
+}
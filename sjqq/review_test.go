@@ -0,0 +1,32 @@
+package sjqq
+
+import (
+	"testing"
+)
+
+func TestParseReviewResponse(t *testing.T) {
+	body := []byte(`{"success":true,"obj":{"hasNext":1,"total":35791,"contextData":"ctx2","commentDetails":[
+{"id":1001,"nickName":"小明","score":5,"createdTime":1501491245,"content":"很好用","versionName":"6.5.10","praiseNum":3},
+{"nickName":"游客","score":1,"createdTime":1501491000,"content":"闪退"}]}}`)
+	page, err := parseReviewResponse(body, "com.tencent.mm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 35791 || page.Next != "ctx2" || len(page.Reviews) != 2 {
+		t.Fatalf("page = %+v", page)
+	}
+	r := page.Reviews[0]
+	if r.ID != "1001" || r.Source != "sjqq" || r.Rating != 5 || r.Version != "6.5.10" || r.Likes != 3 || r.Date.Unix() != 1501491245 {
+		t.Errorf("review = %+v", r)
+	}
+	if len(page.Reviews[1].ID) != 16 {
+		t.Errorf("review without id should be hashed, got %q", page.Reviews[1].ID)
+	}
+
+	if page, _ = parseReviewResponse([]byte(`{"success":true,"obj":{"hasNext":0,"contextData":"x"}}`), "a"); page.Next != "" {
+		t.Errorf("last page next = %q", page.Next)
+	}
+	if _, err = parseReviewResponse([]byte(`<html>`), "a"); err != ErrParse {
+		t.Errorf("err = %v, want ErrParse", err)
+	}
+}
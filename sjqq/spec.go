@@ -0,0 +1,87 @@
+package sjqq
+
+import (
+	"sync"
+)
+
+import (
+	"github.com/Vonng/go-android-search/extract"
+)
+
+// defaultSpec 为内置的详情页抽取规则，页面改版时可通过 SetSpec 覆盖而无需重新部署
+// 页面底部脚本中的 appDetailData 不在规则中，由 AppDetailData 解析后覆盖页面元素的值
+const defaultSpec = `
+scopes:
+  oi: div.det-othinfo-container
+fields:
+  id:           {selector: a.det-down-btn, attr: apk, required: true}
+  name:         {selector: div.det-name-int, required: true}
+  icon:         {selector: "div.app-icon img", attr: src}
+  link:         {selector: a.det-ins-btn, attr: ex_url}
+  size:         {selector: div.det-size, post: [size]}
+  rating:       {selector: div.com-blue-star-num, post: [stars]}
+  rating_value: {selector: div.com-blue-star-num, post: ["trimRight:分"]}
+  install_cnt:  {selector: div.det-ins-num, post: ["trimRight:下载", number]}
+  genre:        {selector: "#J_DetCate"}
+  vendor:       {scope: oi, selector: "div:nth-of-type(6)"}
+  version:      {scope: oi, selector: "div.det-othinfo-data:nth-of-type(2)", post: ["trimLeft:V"]}
+  description:  {selector: "div.det-app-data-info:first-of-type", html: true, post: [text]}
+  description_html: {selector: "div.det-app-data-info:first-of-type", html: true, post: [sanitize]}
+  permissions:  {selector: "ul.det-othinfo-plist div.r", list: true}
+  screenshots:  {selector: "div.pic-img-box img", attr: data-src, list: true}
+  related_apps: {selector: "li.det-about-app-box a.com-install-btn", attr: apk, list: true}
+  sibling_apps: {selector: "li.det-samedeve-app-box a.com-install-btn", attr: apk, list: true}
+  release_time: {selector: "#J_ApkPublishTime", attr: data-apkpublishtime, post: [unix]}
+`
+
+// processors 为应用宝规则可用的后处理函数，另见 extract.Builtins
+// 旧的函数名保留为 size、number 的别名，已有的覆盖文件无需修改
+var processors = map[string]extract.Processor{
+	"bytesToInt":    extract.Builtins["size"],
+	"parseZhNumber": extract.Builtins["number"],
+}
+
+var (
+	specMu  sync.RWMutex
+	builtin = mustCompileSpec(defaultSpec)
+	spec    = builtin
+)
+
+func mustCompileSpec(data string) *extract.Spec {
+	s, err := extract.Parse([]byte(data))
+	if err == nil {
+		err = s.Compile(new(App), processors)
+	}
+	if err != nil {
+		panic("sjqq: invalid builtin spec: " + err.Error())
+	}
+	return s
+}
+
+// DefaultSpec 返回内置的抽取规则
+func DefaultSpec() *extract.Spec {
+	return builtin
+}
+
+// Spec 返回当前使用的抽取规则
+func Spec() *extract.Spec {
+	specMu.RLock()
+	defer specMu.RUnlock()
+	return spec
+}
+
+// SetSpec 以 override 中的字段与范围覆盖内置规则，override 为 nil 时恢复内置规则
+// 规则有误时返回错误，当前规则保持不变
+func SetSpec(override *extract.Spec) error {
+	merged := builtin
+	if override != nil {
+		merged = builtin.Merge(override)
+		if err := merged.Compile(new(App), processors); err != nil {
+			return err
+		}
+	}
+	specMu.Lock()
+	spec = merged
+	specMu.Unlock()
+	return nil
+}
@@ -0,0 +1,155 @@
+package warc
+
+import (
+	"io"
+	"os"
+	"fmt"
+	"sort"
+	"time"
+	"bufio"
+	"strconv"
+	"strings"
+	"compress/gzip"
+	"path/filepath"
+)
+
+// Reader 依次读取 WARC 文件中的记录，支持 gzip 压缩与未压缩的文件
+type Reader struct {
+	r      *bufio.Reader
+	closer io.Closer
+}
+
+// NewReader 从r读取记录，以 gzip 魔数开头时自动解压，多个 gzip 成员依次读取
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &Reader{r: bufio.NewReader(gz)}, nil
+	}
+	return &Reader{r: br}, nil
+}
+
+// Open 打开WARC文件，使用完毕后需调用 Close
+func Open(filename string) (*Reader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// Close 关闭 Open 打开的文件
+func (r *Reader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// Next 返回下一条记录，没有更多记录时返回 io.EOF
+func (r *Reader) Next() (*Record, error) {
+	// 跳过记录之间的空行
+	var line string
+	for line == "" {
+		l, err := r.r.ReadString('\n')
+		if err == io.EOF && l == "" {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, fmt.Errorf("warc: read version: %s", err)
+		}
+		line = strings.TrimRight(l, "\r\n")
+	}
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("warc: invalid version line %q", line)
+	}
+
+	rec := &Record{Fields: make(map[string]string)}
+	length := -1
+	for {
+		l, err := r.r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("warc: read header: %s", err)
+		}
+		if l = strings.TrimRight(l, "\r\n"); l == "" {
+			break
+		}
+		i := strings.IndexByte(l, ':')
+		if i < 0 {
+			return nil, fmt.Errorf("warc: invalid header %q", l)
+		}
+		key, value := strings.TrimSpace(l[:i]), strings.TrimSpace(l[i+1:])
+		switch strings.ToLower(key) {
+		case "warc-type":
+			rec.Type = value
+		case "warc-record-id":
+			rec.ID = value
+		case "warc-date":
+			rec.Date, _ = time.Parse(time.RFC3339, value)
+		case "warc-target-uri":
+			rec.TargetURI = value
+		case "warc-concurrent-to":
+			rec.ConcurrentTo = value
+		case "content-type":
+			rec.ContentType = value
+		case "content-length":
+			if length, err = strconv.Atoi(value); err != nil || length < 0 {
+				return nil, fmt.Errorf("warc: invalid content length %q", value)
+			}
+		default:
+			rec.Fields[key] = value
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("warc: record %s without content length", rec.ID)
+	}
+	rec.Block = make([]byte, length)
+	if _, err := io.ReadFull(r.r, rec.Block); err != nil {
+		return nil, fmt.Errorf("warc: read block of %s: %s", rec.ID, err)
+	}
+	return rec, nil
+}
+
+// Files 返回目录下的所有WARC文件，按文件名排序
+func Files(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.warc", "*.warc.gz"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Responses 依次读取文件中的响应记录并调用fn，fn返回错误时停止
+func Responses(filename string, fn func(*Record) error) error {
+	r, err := Open(filename)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if rec.Type == TypeResponse {
+			if err = fn(rec); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package warc
+
+import (
+	"fmt"
+	"time"
+	"bufio"
+	"bytes"
+	"errors"
+	"net/url"
+	"net/http"
+	"crypto/rand"
+)
+
+// 记录类型
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+)
+
+// Version 为写入的 WARC 版本
+const Version = "WARC/1.0"
+
+// ErrNotResponse 表示记录不是 HTTP 响应
+var ErrNotResponse = errors.New("warc: not a response record")
+
+// Record 是一条 WARC 记录
+type Record struct {
+	Type         string            // WARC-Type
+	ID           string            // WARC-Record-ID，形如 <urn:uuid:...>
+	Date         time.Time         // WARC-Date
+	TargetURI    string            // WARC-Target-URI
+	ContentType  string            // Content-Type
+	ConcurrentTo string            // WARC-Concurrent-To，请求记录指向对应的响应记录
+	Fields       map[string]string // 其他头部
+	Block        []byte            // 记录内容，请求与响应记录为原始的 HTTP 报文
+}
+
+// NewID 生成随机的记录ID
+func NewID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Response 将响应记录解析为 HTTP 响应，响应的 Request 为目标地址上的 GET 请求，
+// 可直接交给 goquery.NewDocumentFromResponse
+func (r *Record) Response() (*http.Response, error) {
+	if r.Type != TypeResponse {
+		return nil, ErrNotResponse
+	}
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(r.Block)), nil)
+	if err != nil {
+		return nil, err
+	}
+	res.Request = &http.Request{Method: http.MethodGet, Header: make(http.Header)}
+	if res.Request.URL, err = url.Parse(r.TargetURI); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,128 @@
+package warc
+
+import (
+	"io"
+	"os"
+	"bytes"
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"net/http/httptest"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vonng/go-android-search/wdj"
+)
+
+func TestArchive(t *testing.T) {
+	page, err := ioutil.ReadFile("../wdj/sample/com.tencent.mm.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "warc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writer, err := NewWriter(dir, "wdj", 1) // 每组记录一个文件
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &Transport{Writer: writer}}
+	for _, path := range []string{"/apps/com.tencent.mm", "/apps/com.tencent.mm?v=2"} {
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if !bytes.Equal(body, page) {
+			t.Fatal("body is changed by archiving transport")
+		}
+	}
+	writer.Close()
+
+	files, err := Files(dir)
+	if err != nil || len(files) != 2 {
+		t.Fatalf("files = %v, %v", files, err)
+	}
+
+	r, err := Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	var req *Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, rec.Type)
+		if rec.Type == TypeRequest {
+			req = rec
+		}
+	}
+	r.Close()
+	if len(types) != 3 || types[0] != TypeWarcinfo || types[1] != TypeRequest || types[2] != TypeResponse {
+		t.Errorf("record types = %v", types)
+	}
+	if req == nil || req.ConcurrentTo == "" || !bytes.HasPrefix(req.Block, []byte("GET /apps/com.tencent.mm HTTP/1.1")) {
+		t.Errorf("request record = %+v", req)
+	}
+
+	// 存档的页面可以重新解析
+	for _, file := range files {
+		err = Responses(file, func(rec *Record) error {
+			res, err := rec.Response()
+			if err != nil {
+				return err
+			}
+			doc, err := goquery.NewDocumentFromResponse(res)
+			if err != nil {
+				return err
+			}
+			if doc.Url.Path != "/apps/com.tencent.mm" {
+				t.Errorf("document url = %s", doc.Url)
+			}
+			app := new(wdj.App)
+			if err = app.Parse(doc); err != nil {
+				return err
+			}
+			if app.ID != "com.tencent.mm" {
+				t.Errorf("parsed id = %s", app.ID)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestReader_Plain(t *testing.T) {
+	data := "WARC/1.0\r\nWARC-Type: resource\r\nWARC-Record-ID: <urn:uuid:1>\r\nX-Custom: a\r\nContent-Length: 5\r\n\r\nhello\r\n\r\n"
+	r, err := NewReader(bytes.NewBufferString(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := r.Next()
+	if err != nil || rec.Type != "resource" || string(rec.Block) != "hello" || rec.Fields["X-Custom"] != "a" {
+		t.Fatalf("record = %+v, %v", rec, err)
+	}
+	if _, err = r.Next(); err != io.EOF {
+		t.Errorf("err = %v, want EOF", err)
+	}
+	if _, err = rec.Response(); err != ErrNotResponse {
+		t.Errorf("err = %v, want ErrNotResponse", err)
+	}
+}
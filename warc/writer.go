@@ -0,0 +1,190 @@
+package warc
+
+import (
+	"io"
+	"os"
+	"fmt"
+	"sync"
+	"time"
+	"bytes"
+	"strconv"
+	"net/http"
+	"io/ioutil"
+	"compress/gzip"
+	"path/filepath"
+	"net/http/httputil"
+)
+
+// Writer 将记录写入目录下按大小轮转的 gzip 压缩 WARC 文件，可并发使用
+// 每条记录单独压缩为一个 gzip 成员，文件在任意记录处截断仍可读取
+type Writer struct {
+	Dir     string // 输出目录
+	Prefix  string // 文件名前缀，文件名形如 `wdj-20170801120000-00001.warc.gz`
+	MaxSize int64  // 单个文件的最大字节数，超过后轮转到新文件，0 表示不轮转
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	seq  int
+}
+
+// NewWriter 创建目录并返回 Writer，文件在写入第一条记录时创建
+func NewWriter(dir, prefix string, maxSize int64) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Writer{Dir: dir, Prefix: prefix, MaxSize: maxSize}, nil
+}
+
+// rotate 关闭当前文件并打开新文件，新文件以 warcinfo 记录开头
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	w.seq++
+	name := fmt.Sprintf("%s-%s-%05d.warc.gz", w.Prefix, time.Now().Format("20060102150405"), w.seq)
+	f, err := os.OpenFile(filepath.Join(w.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	w.file, w.size = f, 0
+	info := []byte("software: go-android-search\r\nformat: WARC File Format 1.0\r\n")
+	return w.write(&Record{Type: TypeWarcinfo, ContentType: "application/warc-fields",
+		Fields: map[string]string{"WARC-Filename": name}, Block: info})
+}
+
+// write 将记录压缩写入当前文件
+func (w *Writer) write(r *Record) error {
+	if r.ID == "" {
+		r.ID = NewID()
+	}
+	if r.Date.IsZero() {
+		r.Date = time.Now()
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	header := []string{
+		Version,
+		"WARC-Type: " + r.Type,
+		"WARC-Record-ID: " + r.ID,
+		"WARC-Date: " + r.Date.UTC().Format(time.RFC3339),
+	}
+	if r.TargetURI != "" {
+		header = append(header, "WARC-Target-URI: "+r.TargetURI)
+	}
+	if r.ConcurrentTo != "" {
+		header = append(header, "WARC-Concurrent-To: "+r.ConcurrentTo)
+	}
+	for k, v := range r.Fields {
+		header = append(header, k+": "+v)
+	}
+	if r.ContentType != "" {
+		header = append(header, "Content-Type: "+r.ContentType)
+	}
+	header = append(header, "Content-Length: "+strconv.Itoa(len(r.Block)))
+	for _, line := range header {
+		io.WriteString(gz, line+"\r\n")
+	}
+	io.WriteString(gz, "\r\n")
+	gz.Write(r.Block)
+	io.WriteString(gz, "\r\n\r\n")
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	n, err := w.file.Write(buf.Bytes())
+	w.size += int64(n)
+	return err
+}
+
+// Write 写入一组记录，同一组记录总在同一个文件中
+func (w *Writer) Write(records ...*Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil || w.MaxSize > 0 && w.size >= w.MaxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	for _, r := range records {
+		if err := w.write(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Archive 将一次请求与响应写为 request 与 response 两条记录，body 为已读取的响应体
+func (w *Writer) Archive(req *http.Request, res *http.Response, body []byte) error {
+	reqBlock, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return err
+	}
+
+	// 响应体已被解压与去除分块编码，按实际内容重写相应头部
+	header := make(http.Header, len(res.Header))
+	for k, v := range res.Header {
+		header[k] = v
+	}
+	header.Del("Content-Length")
+	header.Del("Transfer-Encoding")
+	if res.Uncompressed {
+		header.Del("Content-Encoding")
+	}
+	var resBlock bytes.Buffer
+	fmt.Fprintf(&resBlock, "HTTP/%d.%d %s\r\n", res.ProtoMajor, res.ProtoMinor, res.Status)
+	header.Write(&resBlock)
+	fmt.Fprintf(&resBlock, "Content-Length: %d\r\n\r\n", len(body))
+	resBlock.Write(body)
+
+	uri, now := req.URL.String(), time.Now()
+	resRecord := &Record{Type: TypeResponse, ID: NewID(), Date: now, TargetURI: uri,
+		ContentType: "application/http; msgtype=response", Block: resBlock.Bytes()}
+	reqRecord := &Record{Type: TypeRequest, Date: now, TargetURI: uri, ConcurrentTo: resRecord.ID,
+		ContentType: "application/http; msgtype=request", Block: reqBlock}
+	return w.Write(reqRecord, resRecord)
+}
+
+// Close 关闭当前文件
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// Transport 在获取页面的同时将请求与响应存档，存档失败不影响请求，错误交给 OnError
+type Transport struct {
+	Base    http.RoundTripper // 为空时使用 http.DefaultTransport
+	Writer  *Writer
+	OnError func(req *http.Request, err error)
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	res, err := base.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err = t.Writer.Archive(req, res, body); err != nil && t.OnError != nil {
+		t.OnError(req, err)
+	}
+	return res, nil
+}
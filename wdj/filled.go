@@ -0,0 +1,21 @@
+package wdj
+
+import (
+	"github.com/Vonng/go-android-search/fill"
+)
+
+// unmonitoredFields 不参与填充率统计的字段：固定值、留空的字段与由开发者页面补充的字段
+var unmonitoredFields = []string{
+	"source", "url", "price", "appkey", "app_id", "apk_code", "extra", "sibling_apps", "unmapped_perms", "crawled_time",
+}
+
+// Filled 返回解析后各字段是否被正确填充，用于发现页面改版导致的选择器失效
+// 除非零值外，版本号还须符合格式，选择器取错位置时视为未填充
+func (app *App) Filled() map[string]bool {
+	filled := fill.Fields(app)
+	for _, field := range unmonitoredFields {
+		delete(filled, field)
+	}
+	filled["version"] = fill.IsVersion(app.Version)
+	return filled
+}
@@ -4,17 +4,19 @@ import (
 	"os"
 	"fmt"
 	"time"
-	"sync"
 	"errors"
-	"strings"
 	"net/url"
-	"encoding/json"
+	"strings"
 	"text/template"
 )
 
 import (
+	"github.com/go-pg/pg/orm"
 	"github.com/PuerkitoBio/goquery"
-	"github.com/go-pg/pg"
+	"github.com/Vonng/go-android-search/norm"
+	"github.com/Vonng/go-android-search/perm"
+	"github.com/Vonng/go-android-search/review"
+	"github.com/Vonng/go-android-search/schema"
 )
 
 var ErrParse = errors.New("parse error")
@@ -40,8 +42,11 @@ const (
 ┃ System      ┆ {{.System      }}
 ┃ Platform    ┆ {{.Platform    }}
 ┃ Permissions ┆ {{.Permissions }}
+┃ PermIDs     ┆ {{.PermissionIDs }}
+┃ Unmapped    ┆ {{.UnmappedPerms }}
 ┃ Size        ┆ {{.Size        }}
 ┃ Rating      ┆ {{.Rating      }}
+┃ RatingScore ┆ {{.RatingScore }} ({{.RatingValue }} {{.RatingScale }}, {{.RatingCnt }})
 ┃ InstallCnt  ┆ {{.InstallCnt  }}
 ┃ CommentCnt  ┆ {{.CommentCnt  }}
 ┃ Appkey      ┆ {{.Appkey      }}
@@ -82,188 +87,108 @@ func searchURL(keyword string) string {
 
 // 应用定义
 type App struct {
-	Source      string                  // 数据来源，固定为豌豆荚`wdj`
-	ID          string   `sql:",pk"`    // 标识 id
-	Name        string                  // 名称 name
-	URL         string                  // 页面 url
-	Icon        string                  // 图标 icon
-	Link        string                  // 下载 link
-	Version     string                  // 版本 version
-	Vendor      string                  // 厂商 vendor
-	Genre       string                  // 分类,豌豆荚的分类等于类目categories第一项 genre
-	Tags        []string  `pg:",array"` // 标签 tags
-	Categories  []string  `pg:",array"` // 类目 categories
-	Price       int64                   // 价格 price 留空
-	System      string                  // 系统要求
-	Platform    []string  `pg:",array"` // 平台
-	Permissions []string  `pg:",array"` // 所需权限 permissions
-	Size        int64                   // 大小 size
-	Rating      int64                   // 评分 rank
-	InstallCnt  int64                   // 安装数 install_cnt
-	CommentCnt  int64                   // 评论数 comment_cnt
-	Appkey      string                  // 友盟分配的Appkey，留空
-	AppID       int64                   // 平台分配的应用ID,豌豆荚无 app_id
-	ApkCode     int64                   // 平台分配的Apk代码,豌豆荚无 apk_code
-	Subtitle    string                  // 副标题 subtitle
-	Commentary  string                  // 编辑评论 commentary
-	Description string                  // 应用描述,带有换行符 description
-	Reviews     string                  // 客户评论,JSON数组,每项为三元组`["YYYY-MM-DD",<user>,<content>` reviews
-	News        string                  // 新闻技巧与攻略，JSON数组,每项为[<title>,<src>,<vendor>] news
-	Extra       string                  // 额外信息，目前置空。
-	Screenshots []string  `pg:",array"` // 截图列表 screenshots
-	RelatedApps []string  `pg:",array"` // 推荐的相关应用 related_apps
-	SiblingApps []string  `pg:",array"` // 同一开发者的其他应用，豌豆荚无 sibling_apps
-	ReleaseNote string                  // 最近更新日志,带有换行符 release_note
-	ReleaseTime time.Time               // 最近更新时间 release_time
-	CrawledTime time.Time               // 最近爬取时间 crawl_time
-	tableName   struct{} `sql:"wdj"`
+	Source          string            // 数据来源，固定为豌豆荚`wdj`
+	ID              string            `sql:",pk"` // 标识 id
+	Name            string            // 名称 name
+	URL             string            // 页面 url
+	Icon            string            // 图标 icon
+	Link            string            // 下载 link
+	Version         string            // 版本 version
+	Vendor          string            // 厂商 vendor
+	Genre           string            // 分类,豌豆荚的分类等于类目categories第一项 genre
+	Tags            []string          `pg:",array"` // 标签 tags
+	Categories      []string          `pg:",array"` // 类目 categories
+	Price           int64             // 价格，单位为分 price
+	System          string            // 系统要求
+	Platform        []string          `pg:",array"` // 平台
+	Permissions     []string          `pg:",array"` // 所需权限 permissions
+	PermissionIDs   []string          `pg:",array"` // 映射后的权限名，如 android.permission.READ_SMS permission_ids
+	UnmappedPerms   []string          `pg:",array"` // 无法映射的权限描述，供核对后增补映射表 unmapped_perms
+	Size            int64             // 大小 size
+	Rating          int64             // 评分 rank
+	RatingValue     float64           // 原始评分，即好评率 0~100 rating_value
+	RatingScale     string            // 原始评分的量纲，固定为 percent rating_scale
+	RatingScore     float64           // 归一化到 [0,1] 的评分，可跨来源比较 rating_score
+	RatingCnt       int64             // 评分人数，好评率按评论统计，即评论数 rating_cnt
+	InstallCnt      int64             // 安装数 install_cnt
+	CommentCnt      int64             // 评论数 comment_cnt
+	Appkey          string            // 友盟分配的Appkey，留空
+	AppID           int64             // 平台分配的应用ID,豌豆荚无 app_id
+	ApkCode         int64             // 平台分配的Apk代码,豌豆荚无 apk_code
+	Subtitle        string            // 副标题 subtitle
+	Commentary      string            // 编辑评论 commentary
+	Description     string            // 应用描述,带有换行符 description
+	Reviews         []review.Review   // 详情页上的客户评论,JSONB数组 reviews
+	News            []review.NewsItem // 新闻技巧与攻略,JSONB数组 news
+	Extra           string            // 额外信息，目前置空。
+	Screenshots     []string          `pg:",array"` // 截图列表 screenshots
+	RelatedApps     []string          `pg:",array"` // 推荐的相关应用 related_apps
+	SiblingApps     []string          `pg:",array"` // 同一开发者的其他应用，详情页无此数据，由开发者页面补充 sibling_apps
+	ReleaseNote     string            // 最近更新日志,带有换行符 release_note
+	DescriptionHTML string            // 应用描述，清理后的HTML description_html
+	CommentaryHTML  string            // 编辑评论，清理后的HTML commentary_html
+	ReleaseNoteHTML string            // 最近更新日志，清理后的HTML release_note_html
+	ReleaseTime     time.Time         // 最近更新时间 release_time
+	CrawledTime     time.Time         // 最近爬取时间 crawl_time
+	tableName       struct{}          `sql:"wdj"`
 }
 
+// App_Parse 按抽取规则解析详情页，规则见 defaultSpec 与 SetSpec
 func (app *App) Parse(doc *goquery.Document) error {
-	// quick selectors
-	info := doc.Find("dl.infos-list")
-	nums := doc.Find("div.num-list")
-
-	// app.ID : apk package_name
-	app.ID = getAttr(doc.Find("body"), "data-pn")
-
-	// app.URL
-	app.URL = AppPageURL(app.ID)
-
-	// app.Name
-	app.Name = getText(doc.Find("p.app-name span.title"))
-
-	if app.ID == "" || app.Name == "" {
+	// 选择器与正则定义在抽取规则中，未取到的字段以 schema.org 标注回退，id 与 name 为必需字段
+	spec := Spec()
+	err := spec.Extract(doc, app)
+	app.fallback(schema.Find(doc))
+	if err != nil && spec.Check(app) != nil {
 		return ErrParse
 	}
 
-	// app.Icon
-	app.Icon = getAttr(doc.Find("div.app-icon img"), "src")
-
-	// app.Link
-	app.Link = getAttr(doc.Find("a.install-btn"), "href")
-
-	// app.Size
-	if size := getAttr(info.Find("meta[itemprop=fileSize]"), "content"); size != "" {
-		app.Size, _ = bytesToInt(size)
-	}
-
-	// app.Rating
-	if rank := getText(nums.Find("span.love i")); rank != "" && rank != "暂无" {
-		app.Rating, _ = parsePercentInt(rank)
-	}
+	// app.URL
+	app.URL = AppPageURL(app.ID)
 
 	// app.AppID
 	// app.ApkCode
 	// 豌豆荚无此数据
 
-	// app.InstallCnt
-	if install := getText(nums.Find("i[itemprop=interactionCount]")); install != "" {
-		app.InstallCnt, _ = parseZhNumber(install)
-	}
+	// app.PermissionIDs, app.UnmappedPerms
+	app.PermissionIDs, app.UnmappedPerms = perm.Resolve(app.Permissions)
 
-	// app.CommentCnt
-	if comment := getText(nums.Find("a.comment-open i")); comment != "" {
-		app.CommentCnt, _ = parseZhNumber(comment)
+	// app.RatingScale, app.RatingScore, app.RatingCnt
+	// 豌豆荚的评分为好评率，按评论统计，页面上没有评分人数时即评论数
+	if app.RatingValue > 0 {
+		app.RatingScale = norm.ScalePercent
+		app.RatingScore = norm.Score(app.RatingValue, app.RatingScale)
+		if app.RatingCnt == 0 {
+			app.RatingCnt = app.CommentCnt
+		}
 	}
 
 	// app.Genre
-	// 题材分类是Categories的第一项，在Categories解析后补充设置
-
-	// app.Vendor
-	app.Vendor = getText(info.Find("span.dev-sites"))
-
-	// app.System
-	if system, ok := getFistTextNode(info.Find("dd.perms")); ok {
-		app.System = strings.TrimRight(strings.TrimLeft(system, "Android "), " 以上")
+	// 题材分类是Categories的第一项
+	if len(app.Categories) > 0 {
+		app.Genre = app.Categories[0]
 	}
 
 	// app.Device
 	// 对于豌豆荚始终置空
 
-	// app.Version
-	app.Version = getText(info.Find("dd:nth-last-of-type(3)"))
-
-	// app.Subtitle
-	app.Subtitle = getText(doc.Find("p.tagline"))
-
-	// app.Commentary
-	app.Commentary = getRichText(doc.Find("div.editorComment div.con"))
-
-	// app.Description
-	app.Description = getRichText(doc.Find("div.desc-info div.con"))
-
-	// app.ReleaseNote
-	app.ReleaseNote = getRichText(doc.Find("div.change-info div"))
-
 	// app.News
-	var news [][3]string
-	doc.Find("ul.app-news-list > li").Map(func(ind int, s *goquery.Selection) string {
-		var entry [3]string
-		entry[0] = getText(s.Find("p a"))
-		entry[1] = getAttr(s.Find("p a"), "href")
-		entry[2] = strings.TrimLeft(getText(s.Find("span")), "来自：")
-		if entry[0] == "" || entry[1] == "" {
-			return ""
-		}
-		news = append(news, entry)
-		return ""
-	})
-	if body, err := json.Marshal(news); err == nil {
-		if sb := string(body); sb != "" && sb != "null" {
-			app.News = sb
-		}
-	}
-
-	// app.Reviews
-	var reviews [][3]string
-	doc.Find("ul.comments-list li.normal-li").Map(func(ind int, s *goquery.Selection) string {
-		var review [3]string
-		review[0] = getText(s.Find("p.first span.name"))
-		if t, err := time.Parse(releaseTimeFmt,
-			getText(s.Find("p.first span:last-of-type"))); err == nil {
-			review[1] = t.Format(normalTimeFmt)
+	doc.Find("ul.app-news-list > li").Each(func(ind int, s *goquery.Selection) {
+		item := review.NewsItem{
+			Title:  getText(s.Find("p a")),
+			URL:    getAttr(s.Find("p a"), "href"),
+			Source: strings.TrimLeft(getText(s.Find("span")), "来自："),
 		}
-		review[2] = getText(s.Find("p.cmt-content span"))
-		if review[0] == "" || review[1] == "" || review[2] == "" {
-			return ""
+		if item.Title != "" && item.URL != "" {
+			app.News = append(app.News, item)
 		}
-		reviews = append(reviews, review)
-		return ""
 	})
-	if body, err := json.Marshal(reviews); err == nil {
-		if sb := string(body); sb != "" && sb != "null" {
-			app.Reviews = sb
-		}
-	}
-
-	// app.Permissions
-	app.Permissions = getTextList(doc.Find("span.perms"))
-
-	// app.Screenshots
-	app.Screenshots = getAttrList(doc.Find("img.screenshot-img"), "src")
-
-	// app.Categories
-	app.Categories = getTextList(info.Find("dd.tag-box a"))
-	if len(app.Categories) > 0 {
-		app.Genre = app.Categories[0]
-	}
 
-	// app.Tags
-	app.Tags = getTextList(info.Find("div.tag-box a"))
-
-	// app.RelatedApps
-	app.RelatedApps = getAttrList(doc.Find("ul.relative-download li a.d-btn"), "data-app-pname")
+	// app.Reviews : 详情页上可见的评论，即评论列表第一页
+	app.Reviews = parseReviewPage(doc, app.ID).Reviews
 
 	// app.SiblingApps
-	// 豌豆荚无此数据
-
-	// app.ReleaseTime
-	if ts := getAttr(info.Find("#baidu_time"), "datetime"); ts != "" {
-		if t, err := time.Parse(releaseTimeFmt, ts); err == nil {
-			app.ReleaseTime = t
-		}
-	}
+	// 详情页无此数据，由开发者页面抓取结果补充，见 SetSiblings
 
 	// app.CrawledTime
 	app.CrawledTime = time.Now()
@@ -273,6 +198,16 @@ func (app *App) Parse(doc *goquery.Document) error {
 	return nil
 }
 
+// SetSiblings 根据开发者的全部应用设置 SiblingApps，排除应用自身
+func (app *App) SetSiblings(apps []string) {
+	app.SiblingApps = nil
+	for _, apk := range apps {
+		if apk != app.ID {
+			app.SiblingApps = append(app.SiblingApps, apk)
+		}
+	}
+}
+
 // Print 打印出人类可读版本的应用信息
 func (app *App) Print() {
 	if err := appTmpl.Execute(os.Stdout, app); err != nil {
@@ -281,7 +216,7 @@ func (app *App) Print() {
 	return
 }
 
-func (app *App) Save(db *pg.DB) error {
+func (app *App) Save(db orm.DB) error {
 	_, err := db.Model(app).
 		OnConflict("(id) DO UPDATE").
 		Set("source= ?source").
@@ -298,8 +233,14 @@ func (app *App) Save(db *pg.DB) error {
 		Set("system= ?system").
 		Set("platform= ?platform").
 		Set("permissions= ?permissions").
+		Set("permission_ids= ?permission_ids").
+		Set("unmapped_perms= ?unmapped_perms").
 		Set("size= ?size").
 		Set("rating= ?rating").
+		Set("rating_value= ?rating_value").
+		Set("rating_scale= ?rating_scale").
+		Set("rating_score= ?rating_score").
+		Set("rating_cnt= ?rating_cnt").
 		Set("install_cnt= ?install_cnt").
 		Set("comment_cnt= ?comment_cnt").
 		Set("appkey= ?appkey").
@@ -315,55 +256,11 @@ func (app *App) Save(db *pg.DB) error {
 		Set("related_apps= ?related_apps").
 		Set("sibling_apps= ?sibling_apps").
 		Set("release_note= ?release_note").
+		Set("description_html= ?description_html").
+		Set("commentary_html= ?commentary_html").
+		Set("release_note_html= ?release_note_html").
 		Set("release_time= ?release_time").
 		Set("crawled_time= ?crawled_time").
 		Insert()
 	return err
 }
-
-// Search 会使用豌豆荚搜索，并返回所有搜索出的PackageName
-func Search(keyword string) (apks []string, error error) {
-	// 搜索结果第一页
-	doc, err := goquery.NewDocument(searchURL(keyword))
-	if err != nil {
-		return nil, err
-	}
-
-	// 获取页面上所有的应用PkgName与其他的列表页URL
-	apkMap := sync.Map{}
-	initApks, pages := parseSearchPage(doc)
-	for _, apk := range initApks {
-		apkMap.Store(apk, nil)
-	}
-
-	// 处理后续的页面
-	wg := sync.WaitGroup{}
-	for _, pageURL := range pages {
-		wg.Add(1)
-		go func(pageURL string) {
-			defer wg.Done()
-			if doc, err := goquery.NewDocument(pageURL); err == nil {
-				for _, apk := range getAttrList(doc.Find("li.search-item > a"),
-					"data-app-pname") {
-					apkMap.Store(apk, nil)
-				}
-			}
-		}(pageURL)
-	}
-	wg.Wait()
-
-	apkMap.Range(func(key, value interface{}) bool {
-		apks = append(apks, key.(string))
-		return true
-	})
-
-	// dedupe
-	return
-}
-
-func parseSearchPage(doc *goquery.Document) (apks, pages []string) {
-	// 不是想要的页面
-	apks = getAttrList(doc.Find("li.search-item > a"), "data-app-pname")
-	pages = getAttrList(doc.Find(`a.page-item:not(a.current):not(a.prev-page):not(a.next-page)`), "href")
-	return
-}
@@ -1,7 +1,9 @@
 package wdj
 
 import (
+	"strings"
 	"testing"
+	"path/filepath"
 	"github.com/go-pg/pg"
 	"fmt"
 )
@@ -55,3 +57,30 @@ func TestSearch(t *testing.T) {
 		}
 	}
 }
+
+func TestParseFile(t *testing.T) {
+	for _, filename := range ReadAllFilename("sample") {
+		app, err := ParseFile(filename)
+		if err != nil {
+			t.Errorf("%s: %s", filename, err)
+			continue
+		}
+		if want := strings.TrimSuffix(filepath.Base(filename), ".html"); app.ID != want {
+			t.Errorf("%s: id = %s, want %s", filename, app.ID, want)
+		}
+		for _, field := range []string{"name", "version", "vendor", "description"} {
+			if !app.Filled()[field] {
+				t.Errorf("%s: %s is not filled", filename, field)
+			}
+		}
+		if app.RatingScale != "percent" || app.RatingScore <= 0 || app.RatingScore > 1 || app.RatingCnt != app.CommentCnt {
+			t.Errorf("%s: rating = %v %s %v %d", filename, app.RatingValue, app.RatingScale, app.RatingScore, app.RatingCnt)
+		}
+		if len(app.PermissionIDs) == 0 {
+			t.Errorf("%s: permissions %v, unmapped %v", filename, app.PermissionIDs, app.UnmappedPerms)
+		}
+	}
+	if _, err := ParseFile("sample/not-exist.html"); err == nil {
+		t.Error("missing file should fail")
+	}
+}
@@ -0,0 +1,135 @@
+package wdj
+
+import (
+	"fmt"
+	"errors"
+	"net/url"
+	"strconv"
+	"net/http"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+)
+
+const categoryURLPrefix = "http://www.wandoujia.com/category/"
+
+// DeveloperURLPrefix 为开发者页面地址前缀，开发者ID即详情页上的开发者名称
+var DeveloperURLPrefix = "http://www.wandoujia.com/developer/"
+
+// Charts 为豌豆荚榜单名称与页面地址，页面改版时在此调整
+// 仅收录详情页导航中实际出现的榜单，其他榜单地址确认后在此增补
+var Charts = map[string]string{
+	"hot": "http://www.wandoujia.com/top/app",
+}
+
+// errListEnd 表示列表页不存在，即已越过列表的最后一页
+var errListEnd = errors.New("end of list")
+
+// MaxListPages 为分类与榜单最多抓取的页数
+var MaxListPages = 10
+
+// ListItem 是分类列表或榜单中的一个应用
+type ListItem struct {
+	Pkg      string // 包名
+	Page     int    // 所在页码，从1开始
+	Position int    // 页内位置，从1开始
+	Rank     int    // 在整个列表中的排名，从1开始
+}
+
+// CategoryURL 生成分类列表页URL，分类ID形如 `5014` 或 `5014_710`
+func CategoryURL(id string, page int) string {
+	return listPageURL(categoryURLPrefix+id, page)
+}
+
+// listPageURL 生成列表的第page页URL
+func listPageURL(base string, page int) string {
+	if page <= 1 {
+		return base
+	}
+	return base + "/" + strconv.Itoa(page)
+}
+
+// Category 抓取分类列表，按列表顺序返回其中的应用
+// 第一页之后的页面失败时，返回已获取的应用及错误
+func Category(id string) ([]ListItem, error) {
+	return crawlList(func(page int) string { return CategoryURL(id, page) }, fetchListPage, MaxListPages)
+}
+
+// DeveloperURL 生成开发者应用列表页URL
+func DeveloperURL(id string, page int) string {
+	return listPageURL(DeveloperURLPrefix+url.PathEscape(id), page)
+}
+
+// Developer 抓取开发者页面，返回该开发者的全部应用
+func Developer(id string) ([]ListItem, error) {
+	return crawlList(func(page int) string { return DeveloperURL(id, page) }, fetchListPage, MaxListPages)
+}
+
+// Chart 抓取榜单，name 为 Charts 中的榜单名称，如 hot
+func Chart(name string) ([]ListItem, error) {
+	base, ok := Charts[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown chart %q", name)
+	}
+	return crawlList(func(page int) string { return listPageURL(base, page) }, fetchListPage, MaxListPages)
+}
+
+// fetchListPage 获取列表页上的所有应用包名，页面不存在时返回 errListEnd
+func fetchListPage(pageURL string) ([]string, error) {
+	res, err := Client.Get(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, errListEnd
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("http status %d", res.StatusCode)
+	}
+	doc, err := goquery.NewDocumentFromResponse(res)
+	if err != nil {
+		return nil, err
+	}
+	return parseListPage(doc), nil
+}
+
+// parseListPage 解析分类与榜单页面上的应用包名
+func parseListPage(doc *goquery.Document) []string {
+	if apks := getAttrList(doc.Find("li.card[data-pn]"), "data-pn"); len(apks) > 0 {
+		return apks
+	}
+	return getAttrList(doc.Find("a[data-app-pname]"), "data-app-pname")
+}
+
+// crawlList 逐页抓取列表，直到页面不存在、没有新应用或达到最大页数
+func crawlList(pageURL func(page int) string, fetch func(string) ([]string, error), maxPages int) (items []ListItem, err error) {
+	seen := make(map[string]bool)
+	for page := 1; page <= maxPages; page++ {
+		apks, err := fetch(pageURL(page))
+		if err == errListEnd && page > 1 {
+			break
+		}
+		if err != nil {
+			if page == 1 {
+				return nil, err
+			}
+			return items, PageError{page, pageURL(page), err}
+		}
+		added := 0
+		for i, apk := range apks {
+			if seen[apk] {
+				continue
+			}
+			seen[apk] = true
+			added++
+			items = append(items, ListItem{apk, page, i + 1, len(items) + 1})
+		}
+		if added == 0 {
+			break
+		}
+	}
+	return items, nil
+}
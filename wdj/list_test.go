@@ -0,0 +1,127 @@
+package wdj
+
+import (
+	"fmt"
+	"errors"
+	"strings"
+	"testing"
+	"net/http"
+	"net/http/httptest"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseListPage(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<ul id="j-tag-list">
+<li class="card" data-pn="com.tencent.mm"></li>
+<li class="card" data-pn="com.tencent.mobileqq"></li>
+<li class="card"></li>
+</ul>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apks := parseListPage(doc); strings.Join(apks, ",") != "com.tencent.mm,com.tencent.mobileqq" {
+		t.Errorf("apks = %v", apks)
+	}
+	if u := CategoryURL("5014_710", 2); u != "http://www.wandoujia.com/category/5014_710/2" {
+		t.Errorf("category url = %s", u)
+	}
+}
+
+func TestCrawlList(t *testing.T) {
+	pages := map[string][]string{
+		"p1": {"a", "b"},
+		"p2": {"b", "c"},
+		"p3": {"c"}, // 没有新应用，列表结束
+		"p4": {"d"},
+	}
+	pageURL := func(page int) string { return fmt.Sprintf("p%d", page) }
+	fetch := func(u string) ([]string, error) { return pages[u], nil }
+
+	items, err := crawlList(pageURL, fetch, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, item := range items {
+		got = append(got, fmt.Sprintf("%s:%d:%d:%d", item.Pkg, item.Page, item.Position, item.Rank))
+	}
+	if strings.Join(got, " ") != "a:1:1:1 b:1:2:2 c:2:2:3" {
+		t.Errorf("items = %v", got)
+	}
+
+	if items, _ = crawlList(pageURL, fetch, 1); len(items) != 2 {
+		t.Errorf("max pages is not respected: %d", len(items))
+	}
+
+	items, err = crawlList(pageURL, func(u string) ([]string, error) {
+		if u == "p2" {
+			return nil, errors.New("http status 503")
+		}
+		return pages[u], nil
+	}, 10)
+	if e, ok := err.(PageError); !ok || e.Page != 2 || len(items) != 2 {
+		t.Errorf("partial = %v %v", items, err)
+	}
+
+	// 越过最后一页时页面不存在，列表完整结束；第一页不存在则是错误
+	items, err = crawlList(pageURL, func(u string) ([]string, error) {
+		if u == "p2" {
+			return nil, errListEnd
+		}
+		return pages[u], nil
+	}, 10)
+	if err != nil || len(items) != 2 {
+		t.Errorf("list ends at missing page = %v %v", items, err)
+	}
+	if _, err = crawlList(pageURL, func(string) ([]string, error) { return nil, errListEnd }, 10); err == nil {
+		t.Error("missing first page should fail")
+	}
+}
+
+func TestFetchListPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/top/app":
+			fmt.Fprint(w, `<ul><li class="card" data-pn="com.tencent.mm"></li><li class="card" data-pn="com.tencent.mobileqq"></li></ul>`)
+		case "/top/app/2":
+			fmt.Fprint(w, `<ul></ul>`)
+		case "/busy":
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	charts := Charts
+	defer func() { Charts = charts }()
+
+	for _, c := range []struct {
+		path string
+		want int
+	}{{"/top/app", 2}, {"/top/app/2", 0}} {
+		if apks, err := fetchListPage(server.URL + c.path); err != nil || len(apks) != c.want {
+			t.Errorf("%s = %v %v", c.path, apks, err)
+		}
+	}
+	if _, err := fetchListPage(server.URL + "/top/app/3"); err != errListEnd {
+		t.Errorf("missing page = %v, want errListEnd", err)
+	}
+	if _, err := fetchListPage(server.URL + "/busy"); err == nil || err == errListEnd {
+		t.Errorf("busy page = %v", err)
+	}
+
+	// 短于最大页数的榜单：第2页为空或不存在都完整结束
+	Charts = map[string]string{"hot": server.URL + "/top/app", "short": server.URL + "/top/short"}
+	if items, err := Chart("hot"); err != nil || len(items) != 2 {
+		t.Errorf("chart = %v %v", items, err)
+	}
+	if _, err := Chart("short"); err == nil {
+		t.Error("missing chart should fail")
+	}
+	if _, err := Chart("rising"); err == nil {
+		t.Error("unknown chart should fail")
+	}
+}
@@ -0,0 +1,58 @@
+package wdj
+
+import (
+	"time"
+	"net/url"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vonng/go-android-search/norm"
+	"github.com/Vonng/go-android-search/review"
+)
+
+// ReviewURL 生成应用评论页URL
+func ReviewURL(id string) string {
+	return AppPagePrefix + id + "/comment2"
+}
+
+// FetchReviews 获取应用的一页评论，cursor 为下一页URL，为空时获取第一页，可作为 review.Fetcher
+func FetchReviews(id, cursor string) (*review.Page, error) {
+	if cursor == "" {
+		cursor = ReviewURL(id)
+	}
+	doc, err := fetchPage(cursor)
+	if err != nil {
+		return nil, err
+	}
+	return parseReviewPage(doc, id), nil
+}
+
+// parseReviewPage 解析评论页，豌豆荚评论无ID、评分、版本与点赞数，ID由用户、时间与内容生成。
+// 下一页链接为相对地址，按页面URL解析为绝对地址，从文件读取的页面按 ReviewURL 解析
+func parseReviewPage(doc *goquery.Document, id string) *review.Page {
+	page := new(review.Page)
+	doc.Find("ul.comments-list li.normal-li").Each(func(i int, s *goquery.Selection) {
+		r := review.Review{Source: "wdj", AppID: id}
+		r.User = getText(s.Find("p.first span.name"))
+		r.Content = getText(s.Find("p.cmt-content span"))
+		date := getText(s.Find("p.first span:last-of-type"))
+		t, err := time.ParseInLocation(releaseTimeFmt, date, norm.Location)
+		if r.User == "" || r.Content == "" || err != nil {
+			return
+		}
+		r.Date = t
+		r.ID = review.HashID(r.User, date, r.Content)
+		page.Reviews = append(page.Reviews, r)
+	})
+	if href := getAttr(doc.Find("a.next-page").First(), "href"); href != "" {
+		base := doc.Url
+		if base == nil {
+			base, _ = url.Parse(ReviewURL(id))
+		}
+		if ref, err := url.Parse(href); err == nil {
+			page.Next = base.ResolveReference(ref).String()
+		}
+	}
+	return page
+}
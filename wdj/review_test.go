@@ -0,0 +1,70 @@
+package wdj
+
+import (
+	"testing"
+	"net/http"
+	"io/ioutil"
+	"net/http/httptest"
+)
+
+import (
+	"github.com/Vonng/go-android-search/review"
+)
+
+func TestParseReviewPage(t *testing.T) {
+	doc, err := buildDocumentFromFile("sample/com.tencent.mm.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := parseReviewPage(doc, "com.tencent.mm")
+	if len(page.Reviews) == 0 {
+		t.Fatal("no review parsed")
+	}
+	r := page.Reviews[0]
+	if r.Source != "wdj" || r.AppID != "com.tencent.mm" || r.User != "孤独的自由1491613537" || r.Date.Format("2006-01-02") != "2017-07-29" {
+		t.Errorf("review = %+v", r)
+	}
+	ids := make(map[string]bool)
+	for _, r := range page.Reviews {
+		if len(r.ID) != 16 || ids[r.ID] {
+			t.Errorf("bad or duplicate id %q", r.ID)
+		}
+		ids[r.ID] = true
+	}
+	if page.Next != "" {
+		t.Errorf("detail page has no next review page, got %s", page.Next)
+	}
+}
+
+func TestFetchReviewsPaginate(t *testing.T) {
+	doc, err := buildDocumentFromFile("testdata/comment2/com.tencent.mm.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := parseReviewPage(doc, "com.tencent.mm").Next; next != ReviewURL("com.tencent.mm")+"?page=2" {
+		t.Errorf("next of saved page = %s", next)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file := "testdata/comment2/com.tencent.mm.html"
+		if r.URL.Query().Get("page") == "2" {
+			file = "testdata/comment2/com.tencent.mm.2.html"
+		}
+		body, err := ioutil.ReadFile(file)
+		if r.URL.Path != "/apps/com.tencent.mm/comment2" || err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	page, err := FetchReviews("com.tencent.mm", server.URL+"/apps/com.tencent.mm/comment2")
+	if err != nil || len(page.Reviews) != 2 || page.Next != server.URL+"/apps/com.tencent.mm/comment2?page=2" {
+		t.Fatalf("first page = %+v %v", page, err)
+	}
+	reviews, _, next, err := review.Crawl(FetchReviews, "com.tencent.mm", server.URL+"/apps/com.tencent.mm/comment2", nil, 0)
+	if err != nil || len(reviews) != 3 || next != "" || reviews[2].Content != "略显，臃肿" {
+		t.Errorf("crawl = %+v %s %v", reviews, next, err)
+	}
+}
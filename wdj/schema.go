@@ -0,0 +1,45 @@
+package wdj
+
+import (
+	"html"
+	"strings"
+)
+
+import (
+	"github.com/Vonng/go-android-search/fill"
+	"github.com/Vonng/go-android-search/norm"
+	"github.com/Vonng/go-android-search/schema"
+)
+
+// fallback 以页面上的 schema.org 标注填充抽取规则未取到的字段，sa 为 nil 时不做处理
+// 豌豆荚的 microdata 与页面元素重合，选择器因改版失效时仍可能保留
+func (app *App) fallback(sa *schema.SoftwareApplication) {
+	if sa == nil {
+		return
+	}
+	fill.String(&app.Name, sa.Name)
+	fill.String(&app.Icon, sa.Image)
+	fill.String(&app.Description, sa.Description)
+	// 标注中的描述为纯文本，转义后以 <br> 换行作为 HTML
+	fill.String(&app.DescriptionHTML, strings.Replace(html.EscapeString(strings.TrimSpace(sa.Description)), "\n", "<br>", -1))
+	fill.String(&app.Version, sa.Version)
+	fill.String(&app.Vendor, sa.Author)
+	fill.String(&app.System, strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(sa.OperatingSystem, "Android"), "以上")))
+	fill.List(&app.Categories, sa.Category)
+	fill.List(&app.Screenshots, sa.Screenshots)
+	fill.List(&app.Permissions, sa.Permissions)
+	fill.Int(&app.Rating, sa.Rating())
+	fill.Float(&app.RatingValue, float64(sa.Rating()))
+	fill.Int(&app.RatingCnt, sa.RatingCount)
+	fill.Int(&app.CommentCnt, sa.ReviewCount)
+	fill.Int(&app.Price, sa.Price())
+	if size, ok := norm.Size(sa.FileSize); ok {
+		fill.Int(&app.Size, size)
+	}
+	if cnt, ok := norm.Number(sa.InteractionCount); ok {
+		fill.Int(&app.InstallCnt, cnt)
+	}
+	if app.ReleaseTime.IsZero() {
+		app.ReleaseTime = sa.ReleaseTime()
+	}
+}
@@ -0,0 +1,190 @@
+package wdj
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"errors"
+	"strconv"
+	"net/http"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ErrLowCoverage 表示搜索结果页失败过多，成功页比例低于 SearchOptions.MinCoverage
+var ErrLowCoverage = errors.New("search coverage too low")
+
+// SearchItem 是一条搜索结果，保留其在豌豆荚搜索结果中的排序
+type SearchItem struct {
+	Pkg      string // 包名
+	Page     int    // 所在页码，从1开始
+	Position int    // 页内位置，从1开始
+	Rank     int    // 在全部结果中的排名，从1开始，存在失败页时其后的排名偏小
+}
+
+// Pkgs 返回搜索结果的包名列表，保持排名顺序
+func Pkgs(items []SearchItem) []string {
+	apks := make([]string, len(items))
+	for i, item := range items {
+		apks[i] = item.Pkg
+	}
+	return apks
+}
+
+// PageError 记录获取失败的搜索结果页及原因
+type PageError struct {
+	Page int    // 页码
+	URL  string // 页面URL
+	Err  error  // 最后一次尝试的错误
+}
+
+func (e PageError) Error() string {
+	return fmt.Sprintf("page %d %s: %s", e.Page, e.URL, e.Err)
+}
+
+// SearchResult 是一次搜索的结果
+type SearchResult struct {
+	Keyword string
+	Items   []SearchItem // 按排名排序的搜索结果
+	Pages   int          // 结果页总数，包括第一页
+	Failed  []PageError  // 获取失败的结果页，按页码排序
+}
+
+// Coverage 返回成功获取的结果页比例
+func (r *SearchResult) Coverage() float64 {
+	if r.Pages == 0 {
+		return 0
+	}
+	return float64(r.Pages-len(r.Failed)) / float64(r.Pages)
+}
+
+// SearchOptions 控制搜索后续结果页的获取
+type SearchOptions struct {
+	Concurrency int     // 同时获取的结果页数目，<=0 时为1
+	Retries     int     // 失败结果页的重试次数
+	MinCoverage float64 // 成功页比例低于该值时返回 ErrLowCoverage，0 表示不检查
+}
+
+// DefaultSearchOptions 为 Search 使用的默认选项
+var DefaultSearchOptions = SearchOptions{Concurrency: 4, Retries: 1}
+
+// Search 会使用豌豆荚搜索，并按搜索排名返回所有搜索出的应用
+func Search(keyword string) (*SearchResult, error) {
+	return SearchWith(keyword, DefaultSearchOptions)
+}
+
+// SearchWith 使用指定选项搜索，第一页失败时返回错误
+// 成功页比例低于 MinCoverage 时同时返回已获取的结果与 ErrLowCoverage
+func SearchWith(keyword string, opt SearchOptions) (*SearchResult, error) {
+	// 搜索结果第一页
+	doc, err := fetchPage(searchURL(keyword))
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取页面上所有的应用PkgName与其他的列表页URL
+	apks, pages := parseSearchPage(doc)
+	res := collectSearchPages(apks, pages, opt, func(pageURL string) ([]string, error) {
+		doc, err := fetchPage(pageURL)
+		if err != nil {
+			return nil, err
+		}
+		apks, _ := parseSearchPage(doc)
+		if len(apks) == 0 {
+			return nil, ErrParse
+		}
+		return apks, nil
+	})
+	res.Keyword = keyword
+
+	if opt.MinCoverage > 0 && res.Coverage() < opt.MinCoverage {
+		return res, ErrLowCoverage
+	}
+	return res, nil
+}
+
+// collectSearchPages 以有限并发获取后续结果页并失败重试，按页码与页内位置排序汇总结果
+func collectSearchPages(first []string, pages map[int]string, opt SearchOptions,
+	fetch func(pageURL string) ([]string, error)) *SearchResult {
+	concurrency := opt.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := map[int][]string{1: first}
+	res := &SearchResult{Pages: len(pages) + 1}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for page, pageURL := range pages {
+		wg.Add(1)
+		go func(page int, pageURL string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var apks []string
+			var err error
+			for i := 0; i <= opt.Retries; i++ {
+				if apks, err = fetch(pageURL); err == nil {
+					break
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				res.Failed = append(res.Failed, PageError{page, pageURL, err})
+			} else {
+				results[page] = apks
+			}
+		}(page, pageURL)
+	}
+	wg.Wait()
+	sort.Slice(res.Failed, func(i, j int) bool { return res.Failed[i].Page < res.Failed[j].Page })
+
+	// 按页码与页内位置排序，重复出现的应用以首次出现为准
+	pageNums := make([]int, 0, len(results))
+	for page := range results {
+		pageNums = append(pageNums, page)
+	}
+	sort.Ints(pageNums)
+	seen := make(map[string]bool)
+	for _, page := range pageNums {
+		for i, apk := range results[page] {
+			if seen[apk] {
+				continue
+			}
+			seen[apk] = true
+			res.Items = append(res.Items, SearchItem{apk, page, i + 1, len(res.Items) + 1})
+		}
+	}
+	return res
+}
+
+// fetchPage 获取页面，非200响应视为失败
+func fetchPage(pageURL string) (*goquery.Document, error) {
+	res, err := Client.Get(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("http status %d", res.StatusCode)
+	}
+	return goquery.NewDocumentFromResponse(res)
+}
+
+// parseSearchPage 解析搜索结果页，返回页面上的应用包名，以及其他结果页的页码与URL
+func parseSearchPage(doc *goquery.Document) (apks []string, pages map[int]string) {
+	apks = getAttrList(doc.Find("li.search-item > a"), "data-app-pname")
+	pages = make(map[int]string)
+	doc.Find(`a.page-item:not(a.current):not(a.prev-page):not(a.next-page)`).Each(func(i int, s *goquery.Selection) {
+		page, err := strconv.Atoi(getText(s))
+		if href := getAttr(s, "href"); err == nil && page > 1 && href != "" {
+			pages[page] = href
+		}
+	})
+	return
+}
@@ -0,0 +1,78 @@
+package wdj
+
+import (
+	"fmt"
+	"errors"
+	"strings"
+	"testing"
+	"sync/atomic"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseSearchPage(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<ul>
+<li class="search-item"><a data-app-pname="com.tencent.tmgp.sgame"></a></li>
+<li class="search-item"><a data-app-pname="com.tencent.mm"></a></li>
+</ul>
+<a class="page-item prev-page" href="/search?key=x&page=1">上一页</a>
+<a class="page-item current" href="/search?key=x&page=1">1</a>
+<a class="page-item" href="/search?key=x&page=3">3</a>
+<a class="page-item" href="/search?key=x&page=2">2</a>
+<a class="page-item next-page" href="/search?key=x&page=2">下一页</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apks, pages := parseSearchPage(doc)
+	if strings.Join(apks, ",") != "com.tencent.tmgp.sgame,com.tencent.mm" {
+		t.Errorf("apks = %v", apks)
+	}
+	if len(pages) != 2 || pages[2] != "/search?key=x&page=2" || pages[3] != "/search?key=x&page=3" {
+		t.Errorf("pages = %v", pages)
+	}
+}
+
+func TestCollectSearchPages(t *testing.T) {
+	pages := map[int]string{2: "p2", 3: "p3", 4: "p4"}
+	var running, peak, p3tries int32
+	res := collectSearchPages([]string{"a", "b"}, pages, SearchOptions{Concurrency: 2, Retries: 2},
+		func(pageURL string) ([]string, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				if p := atomic.LoadInt32(&peak); n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			switch pageURL {
+			case "p2":
+				return []string{"c", "a"}, nil
+			case "p3":
+				// 第二次重试成功
+				if atomic.AddInt32(&p3tries, 1) < 3 {
+					return nil, errors.New("timeout")
+				}
+				return []string{"d"}, nil
+			}
+			return nil, errors.New("http status 503")
+		})
+
+	if peak > 2 {
+		t.Errorf("concurrency = %d, want <= 2", peak)
+	}
+	var got []string
+	for _, item := range res.Items {
+		got = append(got, fmt.Sprintf("%s:%d:%d:%d", item.Pkg, item.Page, item.Position, item.Rank))
+	}
+	if strings.Join(got, " ") != "a:1:1:1 b:1:2:2 c:2:1:3 d:3:1:4" {
+		t.Errorf("items = %v", got)
+	}
+	if res.Pages != 4 || len(res.Failed) != 1 || res.Failed[0].Page != 4 || res.Failed[0].Err.Error() != "http status 503" {
+		t.Errorf("failed = %v", res.Failed)
+	}
+	if res.Coverage() != 0.75 {
+		t.Errorf("coverage = %v, want 0.75", res.Coverage())
+	}
+}
@@ -0,0 +1,96 @@
+package wdj
+
+import (
+	"sync"
+)
+
+import (
+	"github.com/Vonng/go-android-search/extract"
+)
+
+// defaultSpec 为内置的详情页抽取规则，页面改版时可通过 SetSpec 覆盖而无需重新部署
+// News、Reviews、Genre 等需要组合多个元素的字段仍在 App.Parse 中解析
+const defaultSpec = `
+scopes:
+  info: dl.infos-list
+  nums: div.num-list
+fields:
+  id:           {selector: body, attr: data-pn, required: true}
+  name:         {selector: "p.app-name span.title", required: true}
+  icon:         {selector: "div.app-icon img", attr: src}
+  link:         {selector: a.install-btn, attr: href}
+  size:         {scope: info, selector: "meta[itemprop=fileSize]", attr: content, post: [size]}
+  rating:       {scope: nums, selector: "span.love i", post: ["skip:暂无", percent]}
+  rating_value: {scope: nums, selector: "span.love i", post: ["skip:暂无", "trimRight:%"]}
+  install_cnt:  {scope: nums, selector: "i[itemprop=interactionCount]", post: [number]}
+  comment_cnt:  {scope: nums, selector: "a.comment-open i", post: [number]}
+  vendor:       {scope: info, selector: span.dev-sites}
+  system:       {scope: info, selector: dd.perms, node: first, post: ["trimLeft:Android ", "trimRight: 以上"]}
+  version:      {scope: info, selector: "dd:nth-last-of-type(3)"}
+  subtitle:     {selector: p.tagline}
+  commentary:   {selector: "div.editorComment div.con", html: true, post: [text]}
+  description:  {selector: "div.desc-info div.con", html: true, post: [text]}
+  release_note: {selector: "div.change-info div", html: true, post: [text]}
+  commentary_html:   {selector: "div.editorComment div.con", html: true, post: [sanitize]}
+  description_html:  {selector: "div.desc-info div.con", html: true, post: [sanitize]}
+  release_note_html: {selector: "div.change-info div", html: true, post: [sanitize]}
+  permissions:  {selector: span.perms, list: true}
+  screenshots:  {selector: img.screenshot-img, attr: src, list: true}
+  categories:   {scope: info, selector: "dd.tag-box a", list: true}
+  tags:         {scope: info, selector: "div.tag-box a", list: true}
+  related_apps: {selector: "ul.relative-download li a.d-btn", attr: data-app-pname, list: true}
+  release_time: {scope: info, selector: "#baidu_time", attr: datetime, post: [date]}
+`
+
+// processors 为豌豆荚规则可用的后处理函数，另见 extract.Builtins
+// 旧的函数名保留为 size、number、percent 的别名，已有的覆盖文件无需修改
+var processors = map[string]extract.Processor{
+	"bytesToInt":      extract.Builtins["size"],
+	"parseZhNumber":   extract.Builtins["number"],
+	"parsePercentInt": extract.Builtins["percent"],
+}
+
+var (
+	specMu  sync.RWMutex
+	builtin = mustCompileSpec(defaultSpec)
+	spec    = builtin
+)
+
+func mustCompileSpec(data string) *extract.Spec {
+	s, err := extract.Parse([]byte(data))
+	if err == nil {
+		err = s.Compile(new(App), processors)
+	}
+	if err != nil {
+		panic("wdj: invalid builtin spec: " + err.Error())
+	}
+	return s
+}
+
+// DefaultSpec 返回内置的抽取规则
+func DefaultSpec() *extract.Spec {
+	return builtin
+}
+
+// Spec 返回当前使用的抽取规则
+func Spec() *extract.Spec {
+	specMu.RLock()
+	defer specMu.RUnlock()
+	return spec
+}
+
+// SetSpec 以 override 中的字段与范围覆盖内置规则，override 为 nil 时恢复内置规则
+// 规则有误时返回错误，当前规则保持不变
+func SetSpec(override *extract.Spec) error {
+	merged := builtin
+	if override != nil {
+		merged = builtin.Merge(override)
+		if err := merged.Compile(new(App), processors); err != nil {
+			return err
+		}
+	}
+	specMu.Lock()
+	spec = merged
+	specMu.Unlock()
+	return nil
+}
@@ -0,0 +1,50 @@
+package wdj
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/Vonng/go-android-search/extract"
+)
+
+func TestSetSpec(t *testing.T) {
+	defer SetSpec(nil)
+	const filename = "sample/com.tencent.mm.html"
+
+	override, err := extract.Parse([]byte(`fields: {id: {selector: body, attr: data-renamed, required: true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = SetSpec(override); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseFile(filename); err != ErrParse {
+		t.Errorf("required id is missing: err = %v, want ErrParse", err)
+	}
+
+	bad, _ := extract.Parse([]byte(`fields: {name: {selector: p, post: [unknown]}}`))
+	if err = SetSpec(bad); err == nil {
+		t.Error("invalid override should be rejected")
+	}
+	if Spec().Fields["id"][0].Attr != "data-renamed" {
+		t.Error("spec should be kept when override is rejected")
+	}
+
+	// 选择器失效的字段由 microdata 回退
+	broken, _ := extract.Parse([]byte(`fields: {name: {selector: "span.renamed", required: true}, size: {selector: "meta.renamed", attr: content, post: [bytesToInt]},
+  description: {selector: "div.renamed"}, description_html: {selector: "div.renamed", html: true}}`))
+	if err = SetSpec(broken); err != nil {
+		t.Fatal(err)
+	}
+	if app, err := ParseFile(filename); err != nil || app.Name != "微信" || app.Size == 0 || app.Description == "" || app.DescriptionHTML == "" {
+		t.Errorf("microdata fallback: app = %v, err = %v", app, err)
+	}
+
+	if err = SetSpec(nil); err != nil {
+		t.Fatal(err)
+	}
+	if app, err := ParseFile(filename); err != nil || app.Name == "" {
+		t.Errorf("builtin spec: app = %v, err = %v", app, err)
+	}
+}
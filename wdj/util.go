@@ -4,14 +4,16 @@ import (
 	"os"
 	"io/ioutil"
 	"strings"
-	"unicode"
+	"net/http"
 )
 
 import (
 	"github.com/PuerkitoBio/goquery"
-	"strconv"
 )
 
+// Client is used for all page fetching, replace it to set timeout or transport
+var Client = http.DefaultClient
+
 /**************************************************************\
 * Auxiliary functions
 ***************************************************************/
@@ -37,17 +39,6 @@ func removeEmpty(input []string) (output []string) {
 	return
 }
 
-// getRichText handles multiline text
-func getRichText(selection *goquery.Selection) (s string) {
-	if s, err := selection.Html(); s != "" && err == nil {
-		s = strings.Replace(s, "<br>", "\n", -1)
-		s = strings.Replace(s, "<br/>", "\n", -1)
-		s = strings.TrimSpace(s)
-		return s
-	}
-	return
-}
-
 // getTextList will fetch a list of text of selectors
 func getTextList(selection *goquery.Selection) []string {
 	res := selection.Map(func(ind int, s *goquery.Selection) string {
@@ -93,137 +84,27 @@ func getLastTextNode(selection *goquery.Selection) (s string, valid bool) {
 	return
 }
 
-// bytesToInt turns "128k, 25 MB" to bytes count
-func bytesToInt(s string) (res int64, ok bool) {
-	var i, nFrac int
-	var val int64
-	var c byte
-	var dot bool
-
-	// parse numeric val (omit dot), and length of frac part
-Loop:
-	for i < len(s) {
-		c = s[i]
-		switch {
-		case '0' <= c && c <= '9':
-			val *= 10
-			val += int64(c - '0')
-			if dot {
-				nFrac ++
-			}
-			i++
-		case c == '.':
-			dot = true
-			i++
-		default:
-			break Loop
-		}
-	}
-	unit := strings.ToUpper(strings.TrimSpace(s[i:]))
-
-	switch unit {
-	case "", "B":
-	case "KB", "K":
-		val <<= 10
-	case "MB", "M":
-		val <<= 20
-	case "GB", "G":
-		val <<= 30
-	case "TB", "T":
-		val <<= 40
-	case "PB", "P":
-		val <<= 50
-	case "EB", "E":
-		val <<= 60
-	default:
-		return 0, false
-	}
-
-	// handle frac
-	for j := 0; j < nFrac; j++ {
-		val /= 10
-	}
-
-	return val, true
-}
-
-// parseZhNumber transform "1.28亿" to corresponding integer
-func parseZhNumber(s string) (res int64, ok bool) {
-	r := []rune(s)
-	n := len(r)
-
-	var mutiplier float64;
-	switch r[n-1] {
-	case rune('万'):
-		mutiplier = 10000
-		r = r[0:n-1]
-	case rune('亿'):
-		mutiplier = 100000000
-		r = r[0:n-1]
-	default:
-		mutiplier = 1
-	}
-
-	numStr := string(r)
-	if dotInd := strings.Index(numStr, "."); dotInd == -1 {
-		// not float dot
-		if i, err := strconv.Atoi(numStr); err != nil {
-			return 0, false
-		} else {
-			return int64(float64(i) * mutiplier), true
-		}
-	} else {
-		// there's a dot, find it's position and shift value
-		for i := 0; i < len(numStr)-dotInd-1; i++ {
-			mutiplier /= 10
-		}
-
-		numStr = strings.Replace(numStr, ".", "", 1)
-		if i, err := strconv.Atoi(numStr); err != nil {
-			return 0, false
-		} else {
-			return int64(float64(i) * mutiplier), true
-		}
-	}
-}
-
-// parsePercentInt will parse "97.00%" to integer 97
-func parsePercentInt(s string) (i int64, ok bool) {
-	s = strings.TrimSpace(strings.TrimRight(s, "%"))
-	if f, err := strconv.ParseFloat(s, 32); err != nil {
-		return 0, false
-	} else {
-		return int64(f), true
-	}
-}
-
-// squeezeTime will parse "2015年05月10日" to "20150510"
-func squeezeTime(s string) string {
-	var nb []rune
-	for _, ch := range s {
-		if unicode.IsDigit(ch) {
-			nb = append(nb, ch)
-		}
-	}
-	return string(nb)
-}
-
 // buildDocumentFromFile will load a goquery document from filepath
 func buildDocumentFromFile(filename string) (doc *goquery.Document, err error) {
-	if f, err := os.Open(filename); err != nil {
+	f, err := os.Open(filename)
+	if err != nil {
 		return nil, err
-	} else {
-		return goquery.NewDocumentFromReader(f)
 	}
+	defer f.Close()
+	return goquery.NewDocumentFromReader(f)
 }
 
 // buildDocumentFromURL will load a goquery document from url
 func buildDocumentFromURL(url string) (doc *goquery.Document, err error) {
-	return goquery.NewDocument(url)
+	res, err := Client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	return goquery.NewDocumentFromResponse(res)
 }
 
 func buildDocumentFromApk(id string) (doc *goquery.Document, err error) {
-	return goquery.NewDocument(AppPageURL(id))
+	return buildDocumentFromURL(AppPageURL(id))
 }
 
 // ReadAllFilename will return a []string contains all file path in that dir
@@ -245,6 +126,19 @@ func ReadAllFilename(dirname string) []string {
 	return buf
 }
 
+// ParseFile will return wandoujia app parsed from a saved detail page
+func ParseFile(filename string) (app *App, err error) {
+	doc, err := buildDocumentFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	app = new(App)
+	if err = app.Parse(doc); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
 // Parse will return wandoujia app by PkgName
 func Parse(id string) (app *App, err error) {
 	doc, err := buildDocumentFromURL(AppPageURL(id))